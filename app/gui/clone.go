@@ -10,6 +10,7 @@ func (m *mainWindow) ecuClone() {
 	if !m.checkSelections() {
 		return
 	}
+	m.progressBar.TextFormatter = nil
 	m.progressBar.Max = 100
 	m.progressBar.SetValue(0)
 	m.disableButtons()
@@ -0,0 +1,49 @@
+package gui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// detectECU probes the bus for a known ECU and preselects it in the ECU list
+func (m *mainWindow) detectECU() {
+	if m.adapterList.SelectedIndex() < 0 {
+		dialog.ShowError(errors.New("Please select an adapter"), m.window) //lint:ignore ST1005 ignore this error
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		state.inprogress = true
+		defer func() {
+			state.inprogress = false
+		}()
+
+		m.disableButtons()
+		defer m.enableButtons()
+
+		m.output("Detecting ECU")
+		d, err := ecu.Detect(ctx, state.adapter, &gocan.AdapterConfig{
+			Port:         state.port,
+			PortBaudrate: state.portBaudrate,
+			OnMessage:    m.output,
+		}, m.output)
+		if err != nil {
+			m.output(err.Error())
+			return
+		}
+
+		m.output("Found " + d.Name)
+		for _, v := range d.Info {
+			m.output(v.String())
+		}
+		m.ecuList.SetSelected(d.Name)
+	}()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"fyne.io/fyne/v2/dialog"
 	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
@@ -32,10 +33,10 @@ func (m *mainWindow) readDTC() {
 		defer c.Close()
 
 		tr, err := ecu.New(c, &ecu.Config{
-			Name:       state.ecuType,
-			OnProgress: m.progress,
-			OnMessage:  m.output,
-			OnError:    m.error,
+			Name:            state.ecuType,
+			OnProgressEvent: m.progress,
+			OnMessage:       m.output,
+			OnError:         m.error,
 		})
 		if err != nil {
 			m.output(err.Error())
@@ -61,3 +62,70 @@ func (m *mainWindow) readDTC() {
 
 	}()
 }
+
+func (m *mainWindow) clearDTC() {
+	if err := ecu.Require(state.ecuType, ecu.CapClearDTC); err != nil {
+		m.output(err.Error())
+		return
+	}
+	if !m.checkSelections() {
+		return
+	}
+	dialog.ShowConfirm("Clear DTC", fmt.Sprintf("Clear all DTC's stored in the %s?", state.ecuType), func(ok bool) {
+		if ok {
+			go m.doClearDTC()
+		}
+	}, m.window)
+}
+
+func (m *mainWindow) doClearDTC() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	state.inprogress = true
+	defer func() {
+		state.inprogress = false
+	}()
+
+	m.disableButtons()
+	defer m.enableButtons()
+
+	c, err := m.initCAN(ctx)
+	if err != nil {
+		m.output(err.Error())
+		return
+	}
+	defer c.Close()
+
+	tr, err := ecu.New(c, &ecu.Config{
+		Name:            state.ecuType,
+		OnProgressEvent: m.progress,
+		OnMessage:       m.output,
+		OnError:         m.error,
+	})
+	if err != nil {
+		m.output(err.Error())
+		return
+	}
+
+	cl, ok := tr.(ecu.DTCClearer)
+	if !ok {
+		m.output(fmt.Errorf("%s: clear DTC: %w", state.ecuType, ecu.ErrNotSupported).Error())
+		return
+	}
+
+	remaining, err := cl.ClearDTC(ctx)
+	if err != nil {
+		m.output(err.Error())
+		return
+	}
+
+	if len(remaining) == 0 {
+		m.output("No DTC's left")
+		return
+	}
+	m.output("DTC's still stored after clearing:")
+	for i, dtc := range remaining {
+		m.output(fmt.Sprintf("#%d %s", i, dtc.String()))
+	}
+}
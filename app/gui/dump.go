@@ -60,10 +60,10 @@ func (m *mainWindow) ecuDump() {
 		defer c.Close()
 
 		tr, err := ecu.New(c, &ecu.Config{
-			Name:       state.ecuType,
-			OnProgress: m.progress,
-			OnMessage:  m.output,
-			OnError:    m.error,
+			Name:            state.ecuType,
+			OnProgressEvent: m.progress,
+			OnMessage:       m.output,
+			OnError:         m.error,
 		})
 		if err != nil {
 			m.output(err.Error())
@@ -53,6 +53,7 @@ func (m *mainWindow) ecuFlash() {
 	}
 
 	m.output("Flashing " + strconv.Itoa(len(bin)) + " bytes")
+	m.progressBar.TextFormatter = nil
 	m.progressBar.SetValue(0)
 	m.progressBar.Max = float64(len(bin))
 	m.progressBar.Refresh()
@@ -73,10 +74,13 @@ func (m *mainWindow) ecuFlash() {
 		defer c.Close()
 
 		tr, err := ecu.New(c, &ecu.Config{
-			Name:       state.ecuType,
-			OnProgress: m.progress,
-			OnMessage:  m.output,
-			OnError:    m.error,
+			Name:            state.ecuType,
+			OnProgressEvent: m.progress,
+			OnMessage:       m.output,
+			OnError:         m.error,
+			OnConfirm: func(question string) bool {
+				return sdialog.Message("%s", question).Title("Are you sure?").YesNo()
+			},
 		})
 		if err != nil {
 			m.output(err.Error())
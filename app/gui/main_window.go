@@ -3,7 +3,6 @@ package gui
 import (
 	"bytes"
 	"fmt"
-	"math"
 	"strconv"
 	"strings"
 
@@ -33,10 +32,13 @@ type mainWindow struct {
 	portList    *widget.Select
 	speedList   *widget.Select
 
+	detectBTN  *widget.Button
 	dtcBTN     *widget.Button
+	clearBTN   *widget.Button
 	infoBTN    *widget.Button
 	dumpBTN    *widget.Button
 	sramBTN    *widget.Button
+	paramBTN   *widget.Button
 	flashBTN   *widget.Button
 	refreshBTN *widget.Button
 
@@ -104,14 +106,17 @@ func (m *mainWindow) layout() *container.Split {
 		m.wizzardBTN,
 		widget.NewLabel(""),
 		m.ecuList,
+		m.detectBTN,
 		m.adapterList,
 		m.portList,
 		m.speedList,
 		layout.NewSpacer(),
 		m.infoBTN,
 		m.dtcBTN,
+		m.clearBTN,
 		m.dumpBTN,
 		m.sramBTN,
+		m.paramBTN,
 		m.flashBTN,
 		m.refreshBTN,
 	)
@@ -126,9 +131,12 @@ func (m *mainWindow) layout() *container.Split {
 
 func (m *mainWindow) createButtons() {
 	m.wizzardBTN = widget.NewButton("Wizzard", m.wizzard)
+	m.detectBTN = widget.NewButton("Detect ECU", m.detectECU)
 	m.dtcBTN = widget.NewButton("Read DTC", m.readDTC)
+	m.clearBTN = widget.NewButton("Clear DTC", m.clearDTC)
 	m.infoBTN = widget.NewButton("Info", m.ecuInfo)
 	m.sramBTN = widget.NewButton("Dump SRAM", m.dumpSRAM)
+	m.paramBTN = widget.NewButton("Parameters", m.editParameters)
 	m.dumpBTN = widget.NewButton("Dump", m.ecuDump)
 	m.flashBTN = widget.NewButton("Flash", m.ecuFlash)
 	m.refreshBTN = widget.NewButton("Refresh Ports", m.refreshPorts)
@@ -144,6 +152,9 @@ func (m *mainWindow) createSelects() {
 		state.ecuType = selected
 		//m.app.Preferences().SetFloat("canrate", state.canRate)
 		m.app.Preferences().SetInt("ecu", m.ecuList.SelectedIndex())
+		if !state.inprogress {
+			m.enableButtons()
+		}
 	})
 
 	m.adapterList = widget.NewSelect(adapter.List(), func(s string) {
@@ -212,10 +223,13 @@ func (m *mainWindow) disableButtons() {
 	m.portList.Disable()
 	m.speedList.Disable()
 
+	m.detectBTN.Disable()
 	m.dtcBTN.Disable()
+	m.clearBTN.Disable()
 	m.infoBTN.Disable()
 	m.dumpBTN.Disable()
 	m.sramBTN.Disable()
+	m.paramBTN.Disable()
 	m.flashBTN.Disable()
 }
 
@@ -224,21 +238,41 @@ func (m *mainWindow) enableButtons() {
 	m.adapterList.Enable()
 	m.portList.Enable()
 	m.speedList.Enable()
+	if m.detectBTN != nil {
+		m.detectBTN.Enable()
+	}
 
-	m.dtcBTN.Enable()
-	m.infoBTN.Enable()
-	m.dumpBTN.Enable()
-	m.sramBTN.Enable()
-	m.flashBTN.Enable()
+	// grey out what the selected ECU can't do
+	caps := ecu.Capabilities(state.ecuType)
+	for _, b := range []struct {
+		btn *widget.Button
+		cap ecu.Capability
+	}{
+		{m.dtcBTN, ecu.CapReadDTC},
+		{m.clearBTN, ecu.CapClearDTC},
+		{m.infoBTN, ecu.CapInfo},
+		{m.dumpBTN, ecu.CapDump},
+		{m.sramBTN, ecu.CapSRAM},
+		{m.paramBTN, ecu.CapParameterWrite},
+		{m.flashBTN, ecu.CapFlash},
+	} {
+		if b.btn == nil {
+			continue
+		}
+		if caps.Has(b.cap) {
+			b.btn.Enable()
+		} else {
+			b.btn.Disable()
+		}
+	}
 }
 
-func (m *mainWindow) progress(t float64) {
-	if t < 0 {
-		m.progressBar.Max = math.Abs(t)
-		m.progressBar.SetValue(0)
-		return
+func (m *mainWindow) progress(p ecu.Progress) {
+	m.progressBar.TextFormatter = func() string {
+		return p.String()
 	}
-	m.progressBar.SetValue(t)
+	m.progressBar.Max = float64(p.Total)
+	m.progressBar.SetValue(float64(p.Done))
 }
 
 func (m *mainWindow) error(err error) {
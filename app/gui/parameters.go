@@ -0,0 +1,139 @@
+package gui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// editParameters reads the ECU parameters and shows them in a form, values
+// changed in the form are written back when it's confirmed
+func (m *mainWindow) editParameters() {
+	if err := ecu.Require(state.ecuType, ecu.CapParameterWrite); err != nil {
+		m.output(err.Error())
+		return
+	}
+	if !m.checkSelections() {
+		return
+	}
+
+	go func() {
+		values := make(map[string]string)
+		var params []ecu.Parameter
+		err := m.withParameterEditor(func(ctx context.Context, pe ecu.ParameterEditor) {
+			params = pe.Parameters()
+			for _, p := range params {
+				v, err := pe.ReadParameter(ctx, p.Name)
+				if err != nil {
+					m.output(err.Error())
+					continue
+				}
+				values[p.Name] = v
+			}
+		})
+		if err != nil {
+			m.output(err.Error())
+			return
+		}
+		if len(values) == 0 {
+			return
+		}
+		m.showParameterForm(params, values)
+	}()
+}
+
+func (m *mainWindow) showParameterForm(params []ecu.Parameter, values map[string]string) {
+	entries := make(map[string]*widget.Entry)
+	var items []*widget.FormItem
+	for _, p := range params {
+		current, ok := values[p.Name]
+		if !ok {
+			continue
+		}
+		e := widget.NewEntry()
+		e.SetText(current)
+		e.Validator = p.Validate
+		entries[p.Name] = e
+		item := widget.NewFormItem(p.Name, e)
+		if p.Unit != "" {
+			item.HintText = p.Unit
+		}
+		items = append(items, item)
+	}
+
+	dialog.ShowForm("Parameters", "Write", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		changed := make(map[string]string)
+		for _, p := range params {
+			e, found := entries[p.Name]
+			if found && e.Text != values[p.Name] {
+				changed[p.Name] = e.Text
+			}
+		}
+		if len(changed) == 0 {
+			m.output("No parameters changed")
+			return
+		}
+		go func() {
+			err := m.withParameterEditor(func(ctx context.Context, pe ecu.ParameterEditor) {
+				for _, p := range params {
+					v, found := changed[p.Name]
+					if !found {
+						continue
+					}
+					if err := pe.WriteParameter(ctx, p.Name, v); err != nil {
+						m.output(err.Error())
+					}
+				}
+			})
+			if err != nil {
+				m.output(err.Error())
+			}
+		}()
+	}, m.window)
+}
+
+// withParameterEditor connects to the ECU and runs fn with it, the ECU is
+// reset once fn returns
+func (m *mainWindow) withParameterEditor(fn func(context.Context, ecu.ParameterEditor)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	state.inprogress = true
+	defer func() {
+		state.inprogress = false
+	}()
+
+	m.disableButtons()
+	defer m.enableButtons()
+
+	c, err := m.initCAN(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	tr, err := ecu.New(c, &ecu.Config{
+		Name:            state.ecuType,
+		OnProgressEvent: m.progress,
+		OnMessage:       m.output,
+		OnError:         m.error,
+	})
+	if err != nil {
+		return err
+	}
+
+	pe, ok := tr.(ecu.ParameterEditor)
+	if !ok {
+		return fmt.Errorf("%s: parameters: %w", state.ecuType, ecu.ErrNotSupported)
+	}
+	fn(ctx, pe)
+
+	return tr.ResetECU(ctx)
+}
@@ -1,5 +1,84 @@
 package gui
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	sdialog "github.com/sqweek/dialog"
+)
+
 func (m *mainWindow) dumpSRAM() {
-	m.output("Not avail yet")
+	if err := ecu.Require(state.ecuType, ecu.CapSRAM); err != nil {
+		m.output(err.Error())
+		return
+	}
+	if !m.checkSelections() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+
+	filename, err := sdialog.File().Filter("Ram file", "ram").Title("Save SRAM dump").Save()
+	if err != nil {
+		m.output(err.Error())
+		cancel()
+		return
+	}
+	filename = addSuffix(filename, ".ram")
+	m.progressBar.SetValue(0)
+
+	go func() {
+		state.inprogress = true
+		defer func() {
+			state.inprogress = false
+		}()
+
+		m.disableButtons()
+		defer m.enableButtons()
+		defer cancel()
+
+		c, err := m.initCAN(ctx)
+		if err != nil {
+			m.output(err.Error())
+			return
+		}
+		defer c.Close()
+
+		tr, err := ecu.New(c, &ecu.Config{
+			Name:            state.ecuType,
+			OnProgressEvent: m.progress,
+			OnMessage:       m.output,
+			OnError:         m.error,
+		})
+		if err != nil {
+			m.output(err.Error())
+			return
+		}
+
+		sd, ok := tr.(ecu.SRAMDumper)
+		if !ok {
+			m.output(fmt.Errorf("%s: SRAM read: %w", state.ecuType, ecu.ErrNotSupported).Error())
+			return
+		}
+
+		bin, err := sd.DumpSRAM(ctx)
+		if err == nil {
+			m.app.SendNotification(fyne.NewNotification("", "SRAM dump done"))
+			if err := os.WriteFile(filename, bin, 0644); err == nil {
+				m.output("Saved as " + filename)
+			} else {
+				m.output(err.Error())
+			}
+		} else {
+			m.output(err.Error())
+		}
+
+		if err := tr.ResetECU(ctx); err != nil {
+			m.output(err.Error())
+		}
+	}()
 }
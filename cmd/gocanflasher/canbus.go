@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+var errUsage = errors.New("invalid usage")
+
+// options shared by all commands talking to an ECU
+type options struct {
+	ecuType      string
+	adapter      string
+	port         string
+	portBaudrate int
+	timeout      time.Duration
+	debug        bool
+	adapterOpts  keyValues
+	// set by the flash command only
+	allowBootWrite bool
+	fixChecksums   bool
+	onConfirm      func(string) bool
+}
+
+// keyValues collects repeated key=value flags
+type keyValues map[string]string
+
+func (kv *keyValues) String() string {
+	var out []string
+	for k, v := range *kv {
+		out = append(out, k+"="+v)
+	}
+	return strings.Join(out, ",")
+}
+
+func (kv *keyValues) Set(s string) error {
+	k, v, found := strings.Cut(s, "=")
+	if !found {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	if *kv == nil {
+		*kv = make(keyValues)
+	}
+	(*kv)[k] = v
+	return nil
+}
+
+func newFlagSet(name string, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&o.ecuType, "ecu", os.Getenv("GOCANFLASHER_ECU"), "ECU type, see list-ecus")
+	fs.StringVar(&o.adapter, "adapter", os.Getenv("GOCANFLASHER_ADAPTER"), "CAN adapter, see list-adapters")
+	fs.StringVar(&o.port, "port", os.Getenv("GOCANFLASHER_PORT"), "serial port for adapters that require one")
+	fs.IntVar(&o.portBaudrate, "baudrate", 115200, "serial port baudrate")
+	fs.DurationVar(&o.timeout, "timeout", 900*time.Second, "timeout for the whole operation")
+	fs.BoolVar(&o.debug, "debug", false, "enable adapter debug output")
+	fs.Var(&o.adapterOpts, "adapter-opt", "additional adapter setting as key=value, can be repeated")
+	return fs
+}
+
+// validate checks the options and that the ECU supports what the command needs
+func (o *options) validate(need ecu.Capability) error {
+	if o.ecuType == "" {
+		return fmt.Errorf("%w: no ECU type set, use -ecu", errUsage)
+	}
+	if o.adapter == "" {
+		return fmt.Errorf("%w: no adapter set, use -adapter", errUsage)
+	}
+	return ecu.Require(o.ecuType, need)
+}
+
+func initCAN(ctx context.Context, o *options) (*gocan.Client, error) {
+	startTime := time.Now()
+	term.message("Init adapter")
+	defer func() {
+		term.message(fmt.Sprintf("Done, took: %s", time.Since(startTime).Round(time.Millisecond).String()))
+	}()
+	dev, err := gocan.NewAdapter(
+		o.adapter,
+		&gocan.AdapterConfig{
+			Debug:            o.debug,
+			Port:             o.port,
+			PortBaudrate:     o.portBaudrate,
+			CANRate:          ecu.CANRate(o.ecuType),
+			CANFilter:        ecu.Filters(o.ecuType),
+			PrintVersion:     true,
+			AdditionalConfig: o.adapterOpts,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := gocan.NewWithOpts(ctx, dev, gocan.WithEventHandler(func(e gocan.Event) {
+		term.message(e.String())
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to init adapter: %w", err)
+	}
+	return c, nil
+}
+
+// withECU opens the adapter, resolves the ECU and runs fn. The ECU is reset
+// afterwards unless noReset is set
+func withECU(ctx context.Context, o *options, need ecu.Capability, noReset bool, fn func(ctx context.Context, tr ecu.Client) error) error {
+	if err := o.validate(need); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	c, err := initCAN(ctx, o)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	tr, err := ecu.New(c, &ecu.Config{
+		Name:            o.ecuType,
+		OnProgressEvent: term.progress,
+		OnMessage:       term.message,
+		OnError:         term.error,
+		OnConfirm:       o.onConfirm,
+		AllowBootWrite:  o.allowBootWrite,
+		FixChecksums:    o.fixChecksums,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx, tr)
+	if noReset || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// use a fresh context, the operation one might have timed out
+	rctx, rcancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer rcancel()
+	if rerr := tr.ResetECU(rctx); rerr != nil {
+		term.message(rerr.Error())
+	}
+	return err
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t7"
+	"github.com/roffe/gocanflasher/pkg/model"
+)
+
+func init() {
+	register(&command{name: "detect", usage: "detect the ECU type on the bus", run: runDetect})
+	register(&command{name: "info", usage: "print ECU information", run: runInfo})
+	register(&command{name: "dtc", usage: "read diagnostic trouble codes", run: runDTC})
+	register(&command{name: "clear-dtc", usage: "clear diagnostic trouble codes", run: runClearDTC})
+	register(&command{name: "live", usage: "read ECU analog inputs", run: runLive})
+	register(&command{name: "dump", usage: "dump ECU flash to a file", run: runDump})
+	register(&command{name: "sram", usage: "dump ECU SRAM to a file", run: runSRAM})
+	register(&command{name: "flash", usage: "flash a bin file to the ECU", run: runFlash})
+	register(&command{name: "verify", usage: "compare a bin file with the ECU flash", run: runVerify})
+	register(&command{name: "erase", usage: "erase ECU flash", run: runErase})
+	register(&command{name: "param", usage: "read or write ECU parameters", run: runParam})
+	register(&command{name: "footer", usage: "show or edit the footer of a Trionic 7 bin file", run: runFooter})
+	register(&command{name: "marry-mcp", usage: "pair a replacement MCP with the main processor", run: runMarryMCP})
+	register(&command{name: "reset", usage: "reset the ECU", run: runReset})
+	register(&command{name: "list-ecus", usage: "list supported ECU types", run: runListECUs})
+	register(&command{name: "list-adapters", usage: "list available CAN adapters", run: runListAdapters})
+}
+
+func runDetect(ctx context.Context, args []string) error {
+	var o options
+	if err := newFlagSet("detect", &o).Parse(args); err != nil {
+		return err
+	}
+	if o.adapter == "" {
+		return fmt.Errorf("%w: no adapter set, use -adapter", errUsage)
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	d, err := ecu.Detect(ctx, o.adapter, &gocan.AdapterConfig{
+		Debug:            o.debug,
+		Port:             o.port,
+		PortBaudrate:     o.portBaudrate,
+		AdditionalConfig: o.adapterOpts,
+	}, term.message)
+	if err != nil {
+		return err
+	}
+	fmt.Println(d.Name)
+	for _, r := range d.Info {
+		fmt.Printf("    %s\n", r.String())
+	}
+	return nil
+}
+
+func runInfo(ctx context.Context, args []string) error {
+	var o options
+	if err := newFlagSet("info", &o).Parse(args); err != nil {
+		return err
+	}
+	return withECU(ctx, &o, ecu.CapInfo, false, func(ctx context.Context, tr ecu.Client) error {
+		return tr.PrintECUInfo(ctx)
+	})
+}
+
+func runDTC(ctx context.Context, args []string) error {
+	var o options
+	if err := newFlagSet("dtc", &o).Parse(args); err != nil {
+		return err
+	}
+	return withECU(ctx, &o, ecu.CapReadDTC, false, func(ctx context.Context, tr ecu.Client) error {
+		dtcs, err := tr.ReadDTC(ctx)
+		if err != nil {
+			return err
+		}
+		printDTCs(dtcs)
+		return nil
+	})
+}
+
+func runClearDTC(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("clear-dtc", &o)
+	yes := fs.Bool("y", false, "do not ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := o.validate(0); err != nil {
+		return err
+	}
+	if !*yes && !confirm(fmt.Sprintf("Clear the DTC's of %s?", o.ecuType)) {
+		return errUserAbort
+	}
+	return withECU(ctx, &o, ecu.CapClearDTC, false, func(ctx context.Context, tr ecu.Client) error {
+		cl, ok := tr.(ecu.DTCClearer)
+		if !ok {
+			return fmt.Errorf("%s: clear DTC: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		remaining, err := cl.ClearDTC(ctx)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			term.message("DTC's still stored after clearing:")
+		}
+		printDTCs(remaining)
+		return nil
+	})
+}
+
+func printDTCs(dtcs []model.DTC) {
+	if len(dtcs) == 0 {
+		term.message("No DTC's")
+		return
+	}
+	for i, dtc := range dtcs {
+		fmt.Printf("#%d %s\n", i, dtc.String())
+	}
+}
+
+func runLive(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("live", &o)
+	channels := fs.String("channels", "", "comma separated channels to read, all if unset")
+	interval := fs.Duration("interval", 0, "keep reading at this interval until ctrl+c")
+	duration := fs.Duration("for", 0, "stop reading at an interval after this long")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var names []string
+	if *channels != "" {
+		names = strings.Split(*channels, ",")
+	}
+	return withECU(ctx, &o, ecu.CapLiveData, false, func(ctx context.Context, tr ecu.Client) error {
+		lr, ok := tr.(ecu.LiveDataReader)
+		if !ok {
+			return fmt.Errorf("%s: live data: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		show := func(readings []ecu.Reading) {
+			out := make([]string, len(readings))
+			for i, r := range readings {
+				out[i] = r.String()
+			}
+			term.message(strings.Join(out, ", "))
+		}
+		if *interval <= 0 {
+			readings, err := lr.ReadLiveData(ctx, names...)
+			if err != nil {
+				return err
+			}
+			show(readings)
+			return nil
+		}
+		// stop polling before the connection times out so the ECU can still
+		// be reset
+		pctx, cancel := context.WithCancel(ctx)
+		if *duration > 0 {
+			pctx, cancel = context.WithTimeout(ctx, *duration)
+		}
+		defer cancel()
+		err := ecu.PollLiveData(pctx, lr, *interval, show, names...)
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			return nil
+		}
+		return err
+	})
+}
+
+func runDump(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("dump", &o)
+	output := fs.String("o", "", "output filename")
+	baseFile := fs.String("base", "", "earlier dump of the same ECU, only changed parts are downloaded")
+	stock := fs.Bool("stock", false, "read without uploading a bootloader, Trionic 8 only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("%w: no output file set, use -o", errUsage)
+	}
+	filename := addSuffix(*output, ".bin")
+	var base []byte
+	if *baseFile != "" {
+		var err error
+		if base, err = os.ReadFile(*baseFile); err != nil {
+			return err
+		}
+	}
+	return withECU(ctx, &o, ecu.CapDump, false, func(ctx context.Context, tr ecu.Client) error {
+		var bin []byte
+		var err error
+		if *stock {
+			sd, ok := tr.(ecu.StockDumper)
+			if !ok {
+				return fmt.Errorf("%s: stock dump: %w", o.ecuType, ecu.ErrNotSupported)
+			}
+			bin, _, err = sd.DumpStock(ctx)
+		} else if base == nil {
+			bin, err = tr.DumpECU(ctx)
+		} else if dd, ok := tr.(ecu.DiffDumper); ok {
+			bin, err = dd.DumpECUDiff(ctx, base)
+		} else {
+			return fmt.Errorf("%s: dump with base: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, bin, 0644); err != nil {
+			return err
+		}
+		term.message("Saved as " + filename)
+		return nil
+	})
+}
+
+func runSRAM(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("sram", &o)
+	output := fs.String("o", "", "output filename")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("%w: no output file set, use -o", errUsage)
+	}
+	filename := addSuffix(*output, ".ram")
+	return withECU(ctx, &o, ecu.CapSRAM, false, func(ctx context.Context, tr ecu.Client) error {
+		sd, ok := tr.(ecu.SRAMDumper)
+		if !ok {
+			return fmt.Errorf("%s: SRAM read: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		bin, err := sd.DumpSRAM(ctx)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, bin, 0644); err != nil {
+			return err
+		}
+		term.message("Saved as " + filename)
+		return nil
+	})
+}
+
+func runFlash(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("flash", &o)
+	input := fs.String("i", "", "bin file to flash")
+	yes := fs.Bool("y", false, "do not ask for confirmation")
+	fs.BoolVar(&o.allowBootWrite, "allow-boot", false, "allow replacing the boot partition, Trionic 8 only")
+	stock := fs.Bool("stock", false, "flash through the factory bootloader, Trionic 8 only")
+	fs.BoolVar(&o.fixChecksums, "fix-checksum", false, "fix bad checksums without asking, Trionic 7 only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	o.onConfirm = func(question string) bool {
+		return !*yes && confirm(question)
+	}
+	if *input == "" {
+		return fmt.Errorf("%w: no input file set, use -i", errUsage)
+	}
+	if err := o.validate(ecu.CapFlash); err != nil {
+		return err
+	}
+	bin, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	if !*yes && !confirm(fmt.Sprintf("Flash %s to %s?", *input, o.ecuType)) {
+		return errUserAbort
+	}
+	return withECU(ctx, &o, ecu.CapFlash, false, func(ctx context.Context, tr ecu.Client) error {
+		if !*stock {
+			return tr.FlashECU(ctx, bin)
+		}
+		sf, ok := tr.(ecu.StockFlasher)
+		if !ok {
+			return fmt.Errorf("%s: stock flash: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		return sf.FlashStock(ctx, bin)
+	})
+}
+
+func runVerify(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("verify", &o)
+	input := fs.String("i", "", "bin file to compare with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("%w: no input file set, use -i", errUsage)
+	}
+	bin, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	return withECU(ctx, &o, 0, false, func(ctx context.Context, tr ecu.Client) error {
+		v, ok := tr.(ecu.Verifier)
+		if !ok {
+			return fmt.Errorf("%s: verify: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		if err := v.Verify(ctx, bin); err != nil {
+			return err
+		}
+		term.message(*input + " matches the ECU")
+		return nil
+	})
+}
+
+func runErase(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("erase", &o)
+	yes := fs.Bool("y", false, "do not ask for confirmation")
+	parts := fs.String("partitions", "", "comma separated partitions to erase instead of the default area, Trionic 8: 2-3 NVDM, 4 HWIO, 5-9 APP")
+	fs.BoolVar(&o.allowBootWrite, "allow-boot", false, "allow erasing the boot partition, Trionic 8 only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	partitions, err := parsePartitions(*parts)
+	if err != nil {
+		return err
+	}
+	if err := o.validate(ecu.CapErase); err != nil {
+		return err
+	}
+	what := o.ecuType
+	if len(partitions) > 0 {
+		what = fmt.Sprintf("partitions %v of %s", partitions, o.ecuType)
+	}
+	if !*yes && !confirm(fmt.Sprintf("Erase %s?", what)) {
+		return errUserAbort
+	}
+	return withECU(ctx, &o, ecu.CapErase, false, func(ctx context.Context, tr ecu.Client) error {
+		if len(partitions) == 0 {
+			return tr.EraseECU(ctx)
+		}
+		pe, ok := tr.(ecu.PartitionEraser)
+		if !ok {
+			return fmt.Errorf("%s: partition erase: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		return pe.ErasePartitions(ctx, partitions)
+	})
+}
+
+func runParam(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("param", &o)
+	set := fs.String("set", "", "parameter to write as name=value, all parameters are read if unset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	need := ecu.CapInfo
+	name, value, write := strings.Cut(*set, "=")
+	if *set != "" {
+		if !write {
+			return fmt.Errorf("%w: -set takes name=value", errUsage)
+		}
+		need = ecu.CapParameterWrite
+	}
+	return withECU(ctx, &o, need, false, func(ctx context.Context, tr ecu.Client) error {
+		pe, ok := tr.(ecu.ParameterEditor)
+		if !ok {
+			return fmt.Errorf("%s: parameters: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		if write {
+			return pe.WriteParameter(ctx, name, value)
+		}
+		for _, p := range pe.Parameters() {
+			v, err := pe.ReadParameter(ctx, p.Name)
+			if err != nil {
+				term.error(err)
+				continue
+			}
+			fmt.Printf("%-12s %s %s\n", p.Name, v, p.Unit)
+		}
+		return nil
+	})
+}
+
+func runFooter(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("footer", flag.ContinueOnError)
+	input := fs.String("i", "", "Trionic 7 bin file")
+	output := fs.String("o", "", "file to write the edited bin to, defaults to the input file")
+	all := fs.Bool("all", false, "list every footer field, unknown ones included")
+	var sets keyValues
+	fs.Var(&sets, "set", "field to change as name=value, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return fmt.Errorf("%w: no input file set, use -i", errUsage)
+	}
+	bin, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	footer, err := t7.ParseFooter(bin)
+	if err != nil {
+		return err
+	}
+	if *all {
+		for _, f := range footer.Fields() {
+			fmt.Println(f.Pretty())
+		}
+		return nil
+	}
+	if len(sets) == 0 {
+		for _, f := range t7.TextFields {
+			fmt.Printf("%-16s %s\n", f.Name, footer.String(f.ID))
+		}
+		return nil
+	}
+
+	for name, value := range sets {
+		f, ok := t7.LookupTextField(name)
+		if !ok {
+			return fmt.Errorf("%w: unknown footer field %q", errUsage, name)
+		}
+		if err := footer.SetString(f.ID, value); err != nil {
+			return err
+		}
+	}
+	out, err := footer.Apply(bin)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = *input
+	}
+	if err := os.WriteFile(*output, out, 0644); err != nil {
+		return err
+	}
+	term.message("Footer written to " + *output)
+	return nil
+}
+
+func runMarryMCP(ctx context.Context, args []string) error {
+	var o options
+	fs := newFlagSet("marry-mcp", &o)
+	yes := fs.Bool("y", false, "do not ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := o.validate(0); err != nil {
+		return err
+	}
+	if !*yes && !confirm(fmt.Sprintf("Marry the MCP of %s?", o.ecuType)) {
+		return errUserAbort
+	}
+	return withECU(ctx, &o, 0, false, func(ctx context.Context, tr ecu.Client) error {
+		m, ok := tr.(ecu.MCPMarrier)
+		if !ok {
+			return fmt.Errorf("%s: marry MCP: %w", o.ecuType, ecu.ErrNotSupported)
+		}
+		return m.MarryMCP(ctx)
+	})
+}
+
+func runReset(ctx context.Context, args []string) error {
+	var o options
+	if err := newFlagSet("reset", &o).Parse(args); err != nil {
+		return err
+	}
+	return withECU(ctx, &o, 0, true, func(ctx context.Context, tr ecu.Client) error {
+		return tr.ResetECU(ctx)
+	})
+}
+
+func runListECUs(_ context.Context, _ []string) error {
+	for _, name := range ecu.List() {
+		fmt.Printf("%-18s %s\n", name, ecu.Capabilities(name))
+	}
+	return nil
+}
+
+func runListAdapters(_ context.Context, _ []string) error {
+	adapters := gocan.ListAdapters()
+	for _, name := range gocan.ListAdapterNames() {
+		for _, a := range adapters {
+			if a.Name != name {
+				continue
+			}
+			serial := ""
+			if a.RequiresSerialPort {
+				serial = " (requires -port)"
+			}
+			fmt.Printf("%s%s\n", a.Name, serial)
+			if a.Description != "" {
+				fmt.Printf("    %s\n", a.Description)
+			}
+		}
+	}
+	return nil
+}
+
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func parsePartitions(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var out []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid partition %q", errUsage, f)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func addSuffix(s, suffix string) string {
+	if !strings.HasSuffix(s, suffix) {
+		return s + suffix
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// Exit codes returned by the CLI so scripts can tell failures apart
+const (
+	exitOK             = 0
+	exitError          = 1
+	exitUsage          = 2
+	exitNoResponse     = 3
+	exitSecurityDenied = 4
+	exitVerifyFailed   = 5
+	exitUserAbort      = 6
+	exitNotSupported   = 7
+)
+
+var errUserAbort = ecu.ErrAborted
+
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) || errors.Is(err, errUsage) {
+		return exitUsage
+	}
+	if errors.Is(err, errUserAbort) || errors.Is(err, context.Canceled) {
+		return exitUserAbort
+	}
+	switch {
+	case errors.Is(err, ecu.ErrNotSupported):
+		return exitNotSupported
+	case errors.Is(err, ecu.ErrSecurityAccessDenied):
+		return exitSecurityDenied
+	case errors.Is(err, ecu.ErrVerifyMismatch):
+		return exitVerifyFailed
+	case errors.Is(err, ecu.ErrNoResponse),
+		errors.Is(err, ecu.ErrBootloaderNotRunning),
+		errors.Is(err, context.DeadlineExceeded):
+		return exitNoResponse
+	}
+	return exitError
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{fmt.Errorf("%w: no -ecu", errUsage), exitUsage},
+		{errUserAbort, exitUserAbort},
+		{fmt.Errorf("flash: %w", ecu.ErrNotSupported), exitNotSupported},
+		{&ecu.NegativeResponse{Service: 0x27, Code: 0x35}, exitSecurityDenied},
+		{fmt.Errorf("md5: %w", ecu.ErrVerifyMismatch), exitVerifyFailed},
+		{fmt.Errorf("read: %w", context.DeadlineExceeded), exitNoResponse},
+		{ecu.ErrBootloaderNotRunning, exitNoResponse},
+		// only the sentinels count, not the wording
+		{errors.New("failed to read checksum: timeout"), exitError},
+		{errors.New("invalid key supplied"), exitError},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
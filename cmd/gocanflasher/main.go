@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+
+	// Import ecu packages
+	_ "github.com/roffe/gocanflasher/pkg/ecu/t5"
+	_ "github.com/roffe/gocanflasher/pkg/ecu/t5legion"
+	_ "github.com/roffe/gocanflasher/pkg/ecu/t7"
+	_ "github.com/roffe/gocanflasher/pkg/ecu/t8"
+	_ "github.com/roffe/gocanflasher/pkg/ecu/t8mcp"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]*command{}
+
+func register(cmd *command) {
+	commands[cmd.name] = cmd
+}
+
+func init() {
+	log.SetFlags(log.Lshortfile | log.LstdFlags)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun '%s <command> -h' for command flags\n", os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	cmd, found := commands[os.Args[1]]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := cmd.run(ctx, os.Args[2:])
+	cancel()
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		term.message(err.Error())
+	}
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,6 @@
+//go:build sim
+
+package main
+
+// Simulated ECUs are only listed as adapters in builds made with -tags sim
+import _ "github.com/roffe/gocanflasher/pkg/sim"
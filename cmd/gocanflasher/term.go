@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+const barWidth = 40
+
+// terminal renders ecu.Config progress and messages on stderr. A message
+// clears the progress line, prints and then redraws the bar below it
+type terminal struct {
+	mu     sync.Mutex
+	w      io.Writer
+	p      ecu.Progress
+	active bool
+}
+
+var term = &terminal{w: os.Stderr}
+
+func (t *terminal) message(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+	fmt.Fprintf(t.w, "%s %s\n", time.Now().Format("15:04:05.000"), s)
+	if t.active {
+		t.draw()
+	}
+}
+
+func (t *terminal) error(err error) {
+	t.message("error: " + err.Error())
+}
+
+// progress redraws the bar, a new phase starts on a fresh line and a
+// finished one is left on screen
+func (t *terminal) progress(p ecu.Progress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p.Phase != t.p.Phase || p.Done < t.p.Done {
+		t.clear()
+	}
+	t.p = p
+	t.active = true
+	t.draw()
+	if p.Done >= p.Total {
+		fmt.Fprintln(t.w)
+		t.active = false
+	}
+}
+
+func (t *terminal) clear() {
+	if t.active {
+		fmt.Fprintf(t.w, "\r%s\r", strings.Repeat(" ", barWidth+70))
+	}
+}
+
+func (t *terminal) draw() {
+	filled := int(t.p.Percent() / 100 * barWidth)
+	line := fmt.Sprintf("\r%-10s [%s%s] %5.1f%% %d/%d", t.p.Phase, strings.Repeat("#", filled), strings.Repeat(" ", barWidth-filled), t.p.Percent(), t.p.Done, t.p.Total)
+	if t.p.Rate > 0 {
+		line += fmt.Sprintf(" %.0f/s", t.p.Rate)
+	}
+	if t.p.ETA > 0 {
+		line += " ETA " + t.p.ETA.Round(time.Second).String()
+	}
+	fmt.Fprint(t.w, line)
+}
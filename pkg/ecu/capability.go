@@ -0,0 +1,85 @@
+package ecu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotSupported is returned by clients for operations the ECU or the
+// implementation can't do
+var ErrNotSupported = errors.New("operation not supported")
+
+// Capability is a set of operations an ECU client supports
+type Capability uint32
+
+const (
+	CapInfo Capability = 1 << iota
+	CapReadDTC
+	CapClearDTC
+	CapDump
+	CapFlash
+	CapErase
+	CapSRAM
+	CapLiveData
+	CapParameterWrite
+)
+
+var capabilityNames = []struct {
+	cap  Capability
+	name string
+}{
+	{CapInfo, "info"},
+	{CapReadDTC, "read DTC"},
+	{CapClearDTC, "clear DTC"},
+	{CapDump, "dump"},
+	{CapFlash, "flash"},
+	{CapErase, "erase"},
+	{CapSRAM, "SRAM read"},
+	{CapLiveData, "live data"},
+	{CapParameterWrite, "parameter write"},
+}
+
+// Has returns true if all capabilities in o are set
+func (c Capability) Has(o Capability) bool {
+	return c&o == o
+}
+
+func (c Capability) String() string {
+	var out []string
+	for _, n := range capabilityNames {
+		if c.Has(n.cap) {
+			out = append(out, n.name)
+		}
+	}
+	if len(out) == 0 {
+		return "none"
+	}
+	return strings.Join(out, ", ")
+}
+
+// Capabilities returns what the named ECU client supports
+func Capabilities(ecuName string) Capability {
+	e, found := ecuMap[ecuName]
+	if !found {
+		return 0
+	}
+	return e.Capabilities
+}
+
+// Supports returns true if the named ECU client supports all of c
+func Supports(ecuName string, c Capability) bool {
+	return Capabilities(ecuName).Has(c)
+}
+
+// Require returns an error wrapping ErrNotSupported if the named ECU client
+// lacks any of c
+func Require(ecuName string, c Capability) error {
+	if _, found := ecuMap[ecuName]; !found {
+		return errors.New("unknown ECU")
+	}
+	if missing := c &^ Capabilities(ecuName); missing != 0 {
+		return fmt.Errorf("%s: %s: %w", ecuName, missing, ErrNotSupported)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ecu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/model"
+)
+
+// ProbeFunc checks if the ECU is on the bus without changing its state and
+// returns whatever identification it could read
+type ProbeFunc func(ctx context.Context, c *gocan.Client) ([]model.HeaderResult, error)
+
+// probeTimeout limits how long each ECU type gets to answer
+const probeTimeout = 2 * time.Second
+
+// Detection is the result of Detect
+type Detection struct {
+	Name string // registered ECU name, usable as Config.Name
+	Info []model.HeaderResult
+}
+
+// Detect probes the bus for every registered ECU that has a Probe, opening
+// the adapter with the CAN rate and filters of each in turn. cfg is used as
+// the base adapter config. The first ECU to answer is returned, if none does
+// the error wraps ErrNoResponse
+func Detect(ctx context.Context, adapterName string, cfg *gocan.AdapterConfig, onMessage func(string)) (*Detection, error) {
+	if onMessage == nil {
+		onMessage = func(string) {}
+	}
+	if cfg == nil {
+		cfg = &gocan.AdapterConfig{}
+	}
+	for _, name := range List() {
+		e := ecuMap[name]
+		if e.Probe == nil {
+			continue
+		}
+		onMessage(fmt.Sprintf("Probing for %s at %g kbit", name, e.CANRate))
+		info, err := probe(ctx, adapterName, cfg, e)
+		if err == nil {
+			return &Detection{Name: name, Info: info}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, ErrNoResponse) {
+			return nil, err
+		}
+		onMessage(fmt.Sprintf("No %s found", name))
+	}
+	return nil, fmt.Errorf("detect: no known ECU answered: %w", ErrNoResponse)
+}
+
+// probe opens the adapter for e and runs its probe, anything but a failure to
+// open the adapter is reported as ErrNoResponse so Detect moves on
+func probe(ctx context.Context, adapterName string, base *gocan.AdapterConfig, e *EcuInfo) ([]model.HeaderResult, error) {
+	cfg := *base
+	cfg.CANRate = e.CANRate
+	cfg.CANFilter = e.Filter
+
+	dev, err := gocan.NewAdapter(adapterName, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	c, err := gocan.NewWithOpts(ctx, dev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init adapter: %w", err)
+	}
+	defer c.Close()
+
+	info, err := e.Probe(ctx, c)
+	if err != nil && !errors.Is(err, ErrNoResponse) {
+		return nil, fmt.Errorf("%w: %w", ErrNoResponse, err)
+	}
+	return info, err
+}
@@ -20,11 +20,66 @@ type Client interface {
 	ResetECU(context.Context) error
 }
 
+// DTCClearer is implemented by clients that can clear trouble codes, the
+// codes still stored afterwards are read back and returned
+type DTCClearer interface {
+	ClearDTC(context.Context) ([]model.DTC, error)
+}
+
+// MCPMarrier is implemented by clients that can pair a replacement MCP with
+// the main processor
+type MCPMarrier interface {
+	MarryMCP(context.Context) error
+}
+
+// PartitionEraser is implemented by clients that can erase single flash
+// partitions instead of the whole flash
+type PartitionEraser interface {
+	ErasePartitions(ctx context.Context, partitions []int) error
+}
+
+// SRAMDumper is implemented by clients that can read the ECU RAM
+type SRAMDumper interface {
+	DumpSRAM(context.Context) ([]byte, error)
+}
+
+// DiffDumper is implemented by clients that can dump the ECU reusing an
+// earlier dump and only downloading what changed
+type DiffDumper interface {
+	DumpECUDiff(ctx context.Context, base []byte) ([]byte, error)
+}
+
+// StockFlasher is implemented by clients that can flash through the factory
+// bootloader instead of an uploaded one
+type StockFlasher interface {
+	FlashStock(ctx context.Context, bin []byte) error
+}
+
+// StockDumper is implemented by clients that can read the flash without
+// uploading a bootloader, refused lists what the ECU would not hand out
+type StockDumper interface {
+	DumpStock(ctx context.Context) (bin []byte, refused []Range, err error)
+}
+
 type Config struct {
-	Name       string
-	OnProgress func(float64)
-	OnError    func(error)
-	OnMessage  func(string)
+	Name string
+	// OnProgress is the old progress callback where a negative value sets the
+	// maximum, it is only used if OnProgressEvent is unset
+	OnProgress      func(float64)
+	OnProgressEvent func(Progress)
+	OnError         func(error)
+	OnMessage       func(string)
+	// OnConfirm asks the user before an optional step, such as fixing a bin,
+	// and returns the answer. Unset declines
+	OnConfirm func(question string) bool
+	// AllowBootWrite lets a flash replace the boot partition on ECUs where
+	// a failed write leaves the ECU unrecoverable over CAN
+	AllowBootWrite bool
+	// FixChecksums corrects bad bin checksums before flashing without
+	// asking through OnConfirm
+	FixChecksums bool
+
+	operation Operation
 }
 
 func LoadConfig(cfg *Config) *Config {
@@ -34,10 +89,13 @@ func LoadConfig(cfg *Config) *Config {
 		}
 	}
 
-	if cfg.OnProgress == nil {
-		cfg.OnProgress = func(f float64) {
-			log.Println(f)
+	if cfg.OnProgressEvent == nil {
+		if cfg.OnProgress == nil {
+			cfg.OnProgress = func(f float64) {
+				log.Println(f)
+			}
 		}
+		cfg.OnProgressEvent = legacyProgress(cfg.OnProgress)
 	}
 
 	if cfg.OnError == nil {
@@ -52,6 +110,12 @@ func LoadConfig(cfg *Config) *Config {
 		}
 	}
 
+	if cfg.OnConfirm == nil {
+		cfg.OnConfirm = func(string) bool {
+			return false
+		}
+	}
+
 	return cfg
 }
 
@@ -62,6 +126,12 @@ type EcuInfo struct {
 	NewFunc func(c *gocan.Client, cfg *Config) Client
 	CANRate float64
 	Filter  []uint32
+	// Capabilities lists the operations the client supports, anything else
+	// returns ErrNotSupported
+	Capabilities Capability
+	// Probe is used by Detect to find the ECU on the bus, ECUs sharing a probe
+	// with another type leave it unset
+	Probe ProbeFunc
 }
 
 func Register(t *EcuInfo) {
@@ -0,0 +1,133 @@
+package ecu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocan/pkg/gmlan"
+)
+
+// Errors wrapped by the ECU packages so callers can tell failures apart with
+// errors.Is
+var (
+	ErrSecurityAccessDenied = errors.New("security access denied")
+	ErrNoResponse           = errors.New("no response from ECU")
+	ErrVerifyMismatch       = errors.New("verification failed")
+	ErrWrongECUType         = errors.New("wrong ECU type")
+	ErrBootloaderNotRunning = errors.New("bootloader not running")
+	// ErrAborted is returned when the user answers OnConfirm with stop
+	ErrAborted = errors.New("aborted by user")
+)
+
+// NegativeResponse is a negative response (0x7F) from the ECU to a KWP2000 or
+// GMLAN request
+type NegativeResponse struct {
+	Service     byte
+	Code        byte   // negative response code
+	Description string // description of Code
+	Err         error  // original protocol error, if any
+}
+
+func (e *NegativeResponse) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("negative response to service 0x%02X: 0x%02X %s", e.Service, e.Code, e.Description)
+}
+
+func (e *NegativeResponse) Unwrap() error {
+	return e.Err
+}
+
+// Is makes the security related response codes match ErrSecurityAccessDenied
+func (e *NegativeResponse) Is(target error) bool {
+	if target == ErrSecurityAccessDenied {
+		switch e.Code {
+		case 0x33, 0x35, 0x36, 0x37:
+			return true
+		}
+	}
+	return false
+}
+
+// NoResponse marks a receive timeout as ErrNoResponse, other errors are
+// returned as is
+func NoResponse(err error) error {
+	if err == nil || errors.Is(err, ErrNoResponse) || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrNoResponse, err)
+}
+
+// SecurityAccess marks a failed security access attempt as
+// ErrSecurityAccessDenied unless the ECU didn't respond at all, run GMLAN
+// requests through GMLAN first to keep the response code
+func SecurityAccess(err error) error {
+	if err == nil {
+		return nil
+	}
+	err = NoResponse(err)
+	if errors.Is(err, ErrNoResponse) || errors.Is(err, ErrSecurityAccessDenied) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrSecurityAccessDenied, err)
+}
+
+// CheckGMLAN checks a GMLAN response frame, a negative response is returned
+// as a NegativeResponse with the service and code bytes from the frame
+func CheckGMLAN(frame *gocan.CANFrame) error {
+	err := gmlan.CheckErr(frame)
+	if err == nil || len(frame.Data) < 4 || frame.Data[1] != 0x7F {
+		return err
+	}
+	return &NegativeResponse{
+		Service:     frame.Data[2],
+		Code:        frame.Data[3],
+		Description: gmlan.TranslateErrorCode(frame.Data[3]),
+		Err:         err,
+	}
+}
+
+// GMLAN runs fn, a gmlan.Client request to an ECU answering on ids. gmlan
+// errors only carry the translated text of a negative response, so the
+// frame is picked up from the bus while fn runs to return the raw service
+// and code bytes. Errors other than negative responses go through NoResponse
+func GMLAN(ctx context.Context, c *gocan.Client, ids []uint32, fn func() error) error {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	negative := make(chan *gocan.CANFrame, 1)
+	sub := c.SubscribeFunc(subCtx, func(f *gocan.CANFrame) {
+		if len(f.Data) < 4 || f.Data[1] != 0x7F {
+			return
+		}
+		// keep the latest, the earlier ones were response pending
+		select {
+		case <-negative:
+		default:
+		}
+		negative <- f
+	}, ids...)
+	// unregister right away, the goroutine behind it may not run for a while
+	defer sub.Close()
+
+	err := fn()
+	var ge *gmlan.GMError
+	if !errors.As(err, &ge) {
+		return NoResponse(err)
+	}
+	// the subscription sees the frame at the same time as gmlan did
+	select {
+	case f := <-negative:
+		return &NegativeResponse{
+			Service:     f.Data[2],
+			Code:        f.Data[3],
+			Description: ge.Code,
+			Err:         err,
+		}
+	case <-time.After(100 * time.Millisecond):
+		return err
+	}
+}
@@ -0,0 +1,70 @@
+package ecu_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocan/pkg/gmlan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8sec"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestCheckGMLAN(t *testing.T) {
+	f := gocan.NewFrame(0x7E8, []byte{0x03, 0x7F, 0x27, 0x35, 0x00, 0x00, 0x00, 0x00}, gocan.Incoming)
+	var nr *ecu.NegativeResponse
+	if err := ecu.CheckGMLAN(f); !errors.As(err, &nr) {
+		t.Fatalf("got %v, want a negative response", err)
+	}
+	if nr.Service != 0x27 || nr.Code != 0x35 {
+		t.Errorf("got service 0x%02X code 0x%02X, want 0x27 0x35", nr.Service, nr.Code)
+	}
+	if !errors.Is(nr, ecu.ErrSecurityAccessDenied) {
+		t.Error("invalid key does not match ErrSecurityAccessDenied")
+	}
+
+	f = gocan.NewFrame(0x7E8, []byte{0x01, 0x50, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, gocan.Incoming)
+	if err := ecu.CheckGMLAN(f); err != nil {
+		t.Errorf("positive response: %v", err)
+	}
+}
+
+func TestGMLANKeepsResponseCode(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	e, err := sim.NewT8(nil, nil, sim.Faults{BadKeys: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := sim.Connect(t, e)
+	gm := gmlan.New(c, 0x7E0, 0x7E8)
+	ids := []uint32{0x7E8}
+
+	// unknown identifier, request out of range
+	err = ecu.GMLAN(ctx, c, ids, func() error {
+		_, err := gm.ReadDataByIdentifier(ctx, 0x55)
+		return err
+	})
+	var nr *ecu.NegativeResponse
+	if !errors.As(err, &nr) {
+		t.Fatalf("got %v, want a negative response", err)
+	}
+	if nr.Service != 0x1A || nr.Code != 0x31 {
+		t.Errorf("got service 0x%02X code 0x%02X, want 0x1A 0x31", nr.Service, nr.Code)
+	}
+
+	// the simulator rejects the first key
+	err = ecu.SecurityAccess(ecu.GMLAN(ctx, c, ids, func() error {
+		return gm.RequestSecurityAccess(ctx, 0x01, 0, t8sec.CalculateAccessKey)
+	}))
+	if !errors.Is(err, ecu.ErrSecurityAccessDenied) {
+		t.Errorf("bad key: got %v, want ErrSecurityAccessDenied", err)
+	}
+	if !errors.As(err, &nr) || nr.Code != 0x35 {
+		t.Errorf("bad key: got %v, want response code 0x35", err)
+	}
+}
@@ -0,0 +1,48 @@
+package ecu
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Reading is one scaled value read from an ECU input
+type Reading struct {
+	Name  string
+	Unit  string
+	Value float64
+	Raw   int
+}
+
+func (r Reading) String() string {
+	return fmt.Sprintf("%s: %.2f %s", r.Name, r.Value, r.Unit)
+}
+
+// LiveDataReader is implemented by clients that can read ECU inputs while
+// connected
+type LiveDataReader interface {
+	// Channels lists the names accepted by ReadLiveData
+	Channels() []string
+	// ReadLiveData reads the named channels, all of them if none are given
+	ReadLiveData(ctx context.Context, names ...string) ([]Reading, error)
+}
+
+// PollLiveData reads the named channels, waiting interval between reads, and
+// hands the readings to fn until ctx is done or a read fails
+func PollLiveData(ctx context.Context, r LiveDataReader, interval time.Duration, fn func([]Reading), names ...string) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		readings, err := r.ReadLiveData(ctx, names...)
+		if err != nil {
+			return err
+		}
+		fn(readings)
+		timer.Reset(interval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
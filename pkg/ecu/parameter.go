@@ -0,0 +1,74 @@
+package ecu
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// ParameterKind tells how a parameter value is written
+type ParameterKind int
+
+const (
+	ParamNumber ParameterKind = iota
+	ParamText
+	// ParamInteger is a number without decimals
+	ParamInteger
+)
+
+// Parameter describes a writable ECU identifier
+type Parameter struct {
+	Name  string
+	PID   byte
+	Unit  string
+	Kind  ParameterKind
+	Scale float64 // raw value per unit, numbers and integers only
+	Min   float64 // valid range, numbers and integers only
+	Max   float64
+	// Length is the exact length of text parameters
+	Length int
+	// SecurityLevel is the security access level needed to write
+	SecurityLevel byte
+}
+
+// Validate checks value can be written to p
+func (p Parameter) Validate(value string) error {
+	switch p.Kind {
+	case ParamText:
+		if len(value) != p.Length {
+			return fmt.Errorf("%s must be %d characters, got %d", p.Name, p.Length, len(value))
+		}
+		for _, c := range value {
+			if c < 0x20 || c > 0x7E {
+				return fmt.Errorf("%s contains invalid character %q", p.Name, c)
+			}
+		}
+	case ParamInteger:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a whole number", p.Name, value)
+		}
+		if float64(v) < p.Min || float64(v) > p.Max {
+			return fmt.Errorf("%s must be between %g and %g %s", p.Name, p.Min, p.Max, p.Unit)
+		}
+	default:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", p.Name, value)
+		}
+		if v < p.Min || v > p.Max {
+			return fmt.Errorf("%s must be between %g and %g %s", p.Name, p.Min, p.Max, p.Unit)
+		}
+	}
+	return nil
+}
+
+// ParameterEditor is implemented by clients that can read and write
+// parameters. Values are passed as text in the parameter unit
+type ParameterEditor interface {
+	Parameters() []Parameter
+	ReadParameter(ctx context.Context, name string) (string, error)
+	// WriteParameter validates value, writes it and reads it back to make
+	// sure the ECU took it
+	WriteParameter(ctx context.Context, name, value string) error
+}
@@ -0,0 +1,32 @@
+package ecu
+
+import "testing"
+
+func TestParameterValidate(t *testing.T) {
+	speed := Parameter{Name: "Top speed", Unit: "km/h", Kind: ParamInteger, Scale: 10, Min: 0, Max: 300}
+	oil := Parameter{Name: "Oil quality", Unit: "%", Scale: 256, Min: 0, Max: 100}
+	vin := Parameter{Name: "VIN", Kind: ParamText, Length: 17}
+	tests := []struct {
+		p     Parameter
+		value string
+		ok    bool
+	}{
+		{speed, "250", true},
+		{speed, "0", true},
+		{speed, "250.5", false},
+		{speed, "2.5e2", false},
+		{speed, "-1", false},
+		{speed, "301", false},
+		{speed, "fast", false},
+		{oil, "55.5", true},
+		{oil, "101", false},
+		{vin, "YS3FH41U581000001", true},
+		{vin, "YS3FH41U58100000", false},
+		{vin, "YS3FH41U58100000\n", false},
+	}
+	for _, tt := range tests {
+		if err := tt.p.Validate(tt.value); (err == nil) != tt.ok {
+			t.Errorf("%s %q: got %v, want ok %v", tt.p.Name, tt.value, err, tt.ok)
+		}
+	}
+}
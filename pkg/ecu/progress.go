@@ -0,0 +1,127 @@
+package ecu
+
+import (
+	"fmt"
+	"time"
+)
+
+// Operation is the top level request a client is working on
+type Operation string
+
+const (
+	OpInfo   Operation = "info"
+	OpDump   Operation = "dump"
+	OpFlash  Operation = "flash"
+	OpErase  Operation = "erase"
+	OpVerify Operation = "verify"
+)
+
+// Phase is a step within an operation
+type Phase string
+
+const (
+	PhaseBootloader Phase = "bootloader"
+	PhaseErase      Phase = "erase"
+	PhaseRead       Phase = "read"
+	PhaseWrite      Phase = "write"
+	PhaseVerify     Phase = "verify"
+)
+
+// Progress is reported by clients while an operation runs. Done and Total
+// are bytes for reads and writes and steps for everything else
+type Progress struct {
+	Operation Operation
+	Phase     Phase
+	Done      int
+	Total     int
+	Rate      float64       // Done units per second since the phase started
+	ETA       time.Duration // estimated time left of the phase, 0 if unknown
+}
+
+// Percent returns how much of the phase is done, 0-100
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Done) / float64(p.Total) * 100
+}
+
+func (p Progress) String() string {
+	s := fmt.Sprintf("%s %s %d/%d %.0f%%", p.Operation, p.Phase, p.Done, p.Total, p.Percent())
+	if p.Rate > 0 {
+		s += fmt.Sprintf(" %.0f/s", p.Rate)
+	}
+	if p.ETA > 0 {
+		s += " ETA " + p.ETA.Round(time.Second).String()
+	}
+	return s
+}
+
+// Begin marks the start of a top level operation, nested calls such as an
+// erase done as part of a flash keep the outer operation. Call the returned
+// func when the operation is done
+func (cfg *Config) Begin(op Operation) func() {
+	if cfg.operation != "" {
+		return func() {}
+	}
+	cfg.operation = op
+	return func() {
+		cfg.operation = ""
+	}
+}
+
+// StartPhase reports the start of a phase of total units and returns a
+// reporter for its progress
+func (cfg *Config) StartPhase(phase Phase, total int) *ProgressReporter {
+	r := &ProgressReporter{
+		cfg:   cfg,
+		start: time.Now(),
+		p: Progress{
+			Operation: cfg.operation,
+			Phase:     phase,
+			Total:     total,
+		},
+	}
+	cfg.OnProgressEvent(r.p)
+	return r
+}
+
+// ProgressReporter keeps track of a phase and fills in rate and ETA
+type ProgressReporter struct {
+	cfg   *Config
+	start time.Time
+	p     Progress
+}
+
+// Update reports done units of the phase total
+func (r *ProgressReporter) Update(done int) {
+	r.p.Done = min(done, r.p.Total)
+	elapsed := time.Since(r.start)
+	r.p.Rate, r.p.ETA = 0, 0
+	if elapsed > 0 && r.p.Done > 0 {
+		r.p.Rate = float64(r.p.Done) / elapsed.Seconds()
+		r.p.ETA = time.Duration(float64(r.p.Total-r.p.Done) / r.p.Rate * float64(time.Second))
+	}
+	r.cfg.OnProgressEvent(r.p)
+}
+
+// Add reports n more units done
+func (r *ProgressReporter) Add(n int) {
+	r.Update(r.p.Done + n)
+}
+
+// Done reports the phase as completed
+func (r *ProgressReporter) Done() {
+	r.Update(r.p.Total)
+}
+
+// legacyProgress feeds progress events to an old style OnProgress callback,
+// where a negative value sets the maximum
+func legacyProgress(fn func(float64)) func(Progress) {
+	return func(p Progress) {
+		if p.Done == 0 {
+			fn(-float64(p.Total))
+		}
+		fn(float64(p.Done))
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/avast/retry-go/v4"
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/srec"
 )
 
@@ -81,7 +82,7 @@ S9035000AC`
 func (t *Client) UploadBootLoader(ctx context.Context) error {
 	start := time.Now()
 
-	t.cfg.OnProgress(-float64(1884))
+	phase := t.cfg.StartPhase(ecu.PhaseBootloader, 1884)
 	t.cfg.OnMessage("Uploading bootloader")
 
 	sr := srec.NewSrec()
@@ -94,7 +95,7 @@ func (t *Client) UploadBootLoader(ctx context.Context) error {
 	for _, rec := range sr.Records {
 		ccrc, err := rec.CalcChecksum()
 		if err != nil {
-			return fmt.Errorf("failed to calculate srec crc: %v", err)
+			return fmt.Errorf("failed to calculate srec crc: %w", err)
 		}
 		if rec.Checksum != ccrc {
 			return fmt.Errorf("srecord CRC: %X does not match calculated CRC: %X", rec.Checksum, ccrc)
@@ -162,7 +163,7 @@ func (t *Client) UploadBootLoader(ctx context.Context) error {
 				if resp.DLC() != 8 || resp.Data[0] != byte(frameNo*7) || resp.Data[1] != 0x00 {
 					return fmt.Errorf("failed to upload bootloader: %X", resp.Data)
 				}
-				t.cfg.OnProgress(progress)
+				phase.Update(int(progress))
 				seq += 7
 			}
 
@@ -187,7 +188,7 @@ func (t *Client) sendBootloaderAddressCommand(ctx context.Context, address uint3
 		0xC,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to sendBootloaderAddressCommand: %v", err)
+		return fmt.Errorf("failed to sendBootloaderAddressCommand: %w", ecu.NoResponse(err))
 	}
 	if f.DLC() != 8 || f.Data[0] != 0xA5 || f.Data[1] != 0x00 {
 		return fmt.Errorf("invalid response to sendBootloaderAddressCommand")
@@ -204,7 +205,7 @@ func (t *Client) sendBootloaderDataCommand(ctx context.Context, data []byte, len
 	frame := gocan.NewFrame(0x5, data, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 150*time.Millisecond, 0xC)
 	if err != nil {
-		return fmt.Errorf("failed SBLDC: %v", err)
+		return fmt.Errorf("failed SBLDC: %w", ecu.NoResponse(err))
 	}
 	if resp.Data[1] != 0x00 {
 		return errors.New("failed to write")
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) GetECUChecksum(ctx context.Context) ([]byte, error) {
@@ -21,7 +22,7 @@ func (t *Client) GetECUChecksum(ctx context.Context) ([]byte, error) {
 	frameData := gocan.NewFrame(0x5, []byte{0xC8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frameData, 1*time.Second, 0xC)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ECU checksum: %v", err)
+		return nil, fmt.Errorf("failed to get ECU checksum: %w", ecu.NoResponse(err))
 	}
 	data := resp.Data[2:6]
 	return data, nil
@@ -0,0 +1,39 @@
+package t5
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/model"
+)
+
+// Probe looks for a Trionic 5. A running bootloader answers the 0xC9 chip
+// query. Stock firmware only answers the bootloader address command, so that
+// is tried next with address 0x5000. This is the first step of a bootloader
+// upload: it sets where uploaded data would go. No data or jump follows it,
+// so nothing is written to RAM or flash and the stock firmware keeps running
+func Probe(ctx context.Context, c *gocan.Client) ([]model.HeaderResult, error) {
+	frame := gocan.NewFrame(0x5, []byte{0xC9, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
+	resp, err := c.SendAndWait(ctx, frame, 150*time.Millisecond, 0xC)
+	if err == nil && resp.DLC() == 8 && resp.Data[0] == 0xC9 && resp.Data[1] == 0x00 {
+		return []model.HeaderResult{
+			{Header: model.Header{Desc: "Bootloader"}, Value: "running"},
+			{Header: model.Header{Desc: "Flash chip"}, Value: fmt.Sprintf("%X", resp.Data[6:])},
+		}, nil
+	}
+
+	frame = gocan.NewFrame(0x5, []byte{0xA5, 0x00, 0x00, 0x50, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
+	resp, err = c.SendAndWait(ctx, frame, 250*time.Millisecond, 0xC)
+	if err != nil {
+		return nil, ecu.NoResponse(err)
+	}
+	if resp.DLC() != 8 || resp.Data[0] != 0xA5 || resp.Data[1] != 0x00 {
+		return nil, fmt.Errorf("%w: unexpected response %X", ecu.ErrWrongECUType, resp.Data)
+	}
+	return []model.HeaderResult{
+		{Header: model.Header{Desc: "Bootloader"}, Value: "not loaded"},
+	}, nil
+}
@@ -0,0 +1,45 @@
+package t5
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+// shortReply answers every frame on 0x005 with its first two bytes
+type shortReply struct{}
+
+func (shortReply) Handle(a *sim.Adapter, f *gocan.CANFrame) {
+	if f.Identifier == 0x005 {
+		a.Reply(0x00C, f.Data[0], 0x00)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	e, err := sim.NewT5(nil, 0, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Probe(ctx, sim.Connect(t, e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Value != "not loaded" {
+		t.Errorf("got %v, want a stock ECU", res)
+	}
+	if e.Running() {
+		t.Error("probe started the bootloader")
+	}
+
+	if _, err := Probe(ctx, sim.Connect(t, shortReply{})); !errors.Is(err, ecu.ErrWrongECUType) {
+		t.Errorf("short frames: got %v, want %v", err, ecu.ErrWrongECUType)
+	}
+}
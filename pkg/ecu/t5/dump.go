@@ -8,9 +8,11 @@ import (
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
 	if !t.bootloaded {
 		if err := t.UploadBootLoader(ctx); err != nil {
 			return nil, err
@@ -25,7 +27,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 	start := getstartAddress(ecutype)
 	length := 0x80000 - start
 
-	t.cfg.OnProgress(-float64(length))
+	phase := t.cfg.StartPhase(ecu.PhaseRead, int(length))
 	t.cfg.OnMessage("Dumping ECU")
 
 	buffer := make([]byte, length)
@@ -54,7 +56,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 			return nil, err
 		}
 		address += 6
-		t.cfg.OnProgress(float64(progress))
+		phase.Update(progress)
 	}
 
 	// Get the leftover bytes
@@ -80,10 +82,10 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 		if err != nil {
 			return nil, err
 		}
-		t.cfg.OnProgress(float64(progress))
+		phase.Update(progress)
 	}
 
-	t.cfg.OnProgress(float64(length))
+	phase.Done()
 	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(startTime).Round(time.Millisecond).String()))
 
 	checksum, err := t.GetECUChecksum(ctx)
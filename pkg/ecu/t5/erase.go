@@ -6,27 +6,29 @@ import (
 	"time"
 
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) EraseECU(ctx context.Context) error {
+	defer t.cfg.Begin(ecu.OpErase)()
 	startTime := time.Now()
 	if !t.bootloaded {
 		if err := t.UploadBootLoader(ctx); err != nil {
 			return err
 		}
 	}
-	t.cfg.OnProgress(-float64(100))
+	phase := t.cfg.StartPhase(ecu.PhaseErase, 1)
 	t.cfg.OnMessage("Erasing FLASH...")
 
 	cmd := []byte{0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
 	frame := gocan.NewFrame(0x005, cmd, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 20*time.Second, 0xC)
 	if err != nil {
-		return err
+		return ecu.NoResponse(err)
 	}
 	if resp.Data[0] == 0xC0 && resp.Data[1] == 0x00 {
 		t.cfg.OnMessage(fmt.Sprintf("FLASH erased, took: %s\n", time.Since(startTime).Round(time.Millisecond).String()))
-		t.cfg.OnProgress(float64(100))
+		phase.Done()
 		return nil
 	}
 
@@ -7,16 +7,14 @@ import (
 	"strings"
 )
 
-var ecuFooter []byte
-
 func (t *Client) GetECUFooter(ctx context.Context) ([]byte, error) {
 	//if !t.bootloaded {
 	//	if err := t.UploadBootLoader(ctx); err != nil {
 	//		return err
 	//	}
 	//}
-	if len(ecuFooter) > 0 {
-		return ecuFooter, nil
+	if len(t.ecuFooter) > 0 {
+		return t.ecuFooter, nil
 	}
 
 	footer := make([]byte, 0x80)
@@ -25,7 +23,7 @@ func (t *Client) GetECUFooter(ctx context.Context) ([]byte, error) {
 	for i := 0; i < (0x80 / 6); i++ {
 		b, err := t.ReadMemoryByAddress(ctx, address)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get ECU footer: %v", err)
+			return nil, fmt.Errorf("failed to get ECU footer: %w", err)
 		}
 		for j := 0; j < 6; j++ {
 			footer[(i*6)+j] = b[j]
@@ -34,14 +32,14 @@ func (t *Client) GetECUFooter(ctx context.Context) ([]byte, error) {
 	}
 	lastBytes, err := t.ReadMemoryByAddress(ctx, 0x7FFFF)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ECU footer: %v", err)
+		return nil, fmt.Errorf("failed to get ECU footer: %w", err)
 	}
 
 	for j := 2; j < 6; j++ {
 		footer[(0x80-6)+j] = lastBytes[j]
 	}
 
-	ecuFooter = footer
+	t.ecuFooter = footer
 	return footer, nil
 }
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) ResetECU(ctx context.Context) error {
@@ -16,10 +17,11 @@ func (t *Client) ResetECU(ctx context.Context) error {
 	//	}
 	//}
 	//log.Println("Resetting ECU")
+	defer t.invalidate()
 	frame := gocan.NewFrame(0x5, []byte{0xC2, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 150*time.Millisecond, 0xC)
 	if err != nil {
-		return fmt.Errorf("failed to reset ECU: %v", err)
+		return fmt.Errorf("failed to reset ECU: %w", ecu.NoResponse(err))
 	}
 	if resp.Data[0] != 0xC2 || resp.Data[1] != 0x00 || resp.Data[2] != 0x08 {
 		return errors.New("invalid response to reset ECU")
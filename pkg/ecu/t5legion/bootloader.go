@@ -10,18 +10,19 @@ import (
 
 	"github.com/avast/retry-go/v4"
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) Ping(ctx context.Context) error {
 	frame := gocan.NewFrame(0x05, []byte{0xEF, 0xBE, 0x00, 0x00, 0x00, 0x00, 0x33, 0x66}, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout, 0x0C)
 	if err != nil {
-		return errors.New("LegionPing: " + err.Error())
+		return fmt.Errorf("LegionPing: %w", ecu.NoResponse(err))
 	}
 	if resp.Data[0] == 0xDE && resp.Data[1] == 0xAD && resp.Data[2] == 0xF0 && resp.Data[3] == 0x0F {
 		return nil
 	}
-	return errors.New("LegionPing: no response")
+	return fmt.Errorf("LegionPing: %w", ecu.ErrNoResponse)
 }
 
 func (t *Client) UploadBootLoader(ctx context.Context) error {
@@ -40,7 +41,7 @@ func (t *Client) UploadBootLoader(ctx context.Context) error {
 	}
 
 	start := time.Now()
-	t.cfg.OnProgress(-float64(len(LegionBootloader)))
+	phase := t.cfg.StartPhase(ecu.PhaseBootloader, len(LegionBootloader))
 	t.cfg.OnMessage("Uploading legion bootloader...")
 	var progress float64 = 0
 	r := bytes.NewReader(LegionBootloader)
@@ -63,7 +64,7 @@ func (t *Client) UploadBootLoader(ctx context.Context) error {
 				0xC,
 			)
 			if err != nil {
-				return fmt.Errorf("failed to sendBootloaderAddressCommand: %v", err)
+				return fmt.Errorf("failed to sendBootloaderAddressCommand: %w", ecu.NoResponse(err))
 			}
 			if f.DLC() != 8 || f.Data[0] != 0xA5 || f.Data[1] != 0x00 {
 				return fmt.Errorf("invalid response to sendBootloaderAddressCommand")
@@ -103,7 +104,7 @@ func (t *Client) UploadBootLoader(ctx context.Context) error {
 		}
 
 		progress += 8
-		t.cfg.OnProgress(progress)
+		phase.Update(int(progress))
 	}
 	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).Round(time.Millisecond).String()))
 	t.bootloaded = true
@@ -117,7 +118,7 @@ func (t *Client) readDataByLocalIdentifier(ctx context.Context, pci byte, addres
 	//log.Println(frame.ColorString())
 	resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout, 0x0C)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, ecu.NoResponse(err)
 	}
 	//log.Println(resp.(*gocan.Frame).ColorString())
 
@@ -158,7 +159,7 @@ func (t *Client) readDataByLocalIdentifier(ctx context.Context, pci byte, addres
 				seq = 0x20
 			}
 		case <-time.After(1 * time.Second):
-			return nil, 0, errors.New("timeout")
+			return nil, 0, fmt.Errorf("read data: %w", ecu.ErrNoResponse)
 		}
 
 	}
@@ -175,7 +176,7 @@ func (t *Client) sendBootloaderAddressCommand(ctx context.Context, address uint3
 		0xC,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to sendBootloaderAddressCommand: %v", err)
+		return fmt.Errorf("failed to sendBootloaderAddressCommand: %w", ecu.NoResponse(err))
 	}
 	if f.DLC() != 8 || f.Data[0] != 0xA5 || f.Data[1] != 0x00 {
 		return fmt.Errorf("invalid response to sendBootloaderAddressCommand")
@@ -194,7 +195,7 @@ func (t *Client) sendBootloaderDataCommand(ctx context.Context, data []byte, len
 	frame := gocan.NewFrame(0x5, data, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 150*time.Millisecond, 0xC)
 	if err != nil {
-		return fmt.Errorf("failed SBLDC: %v", err)
+		return fmt.Errorf("failed SBLDC: %w", ecu.NoResponse(err))
 	}
 	if resp.Data[1] != 0x00 {
 		return errors.New("failed to write")
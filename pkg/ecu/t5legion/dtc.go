@@ -2,11 +2,11 @@ package t5legion
 
 import (
 	"context"
-	"errors"
 
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/model"
 )
 
 func (t *Client) ReadDTC(ctx context.Context) ([]model.DTC, error) {
-	return nil, errors.New("not implemented yet")
+	return nil, ecu.ErrNotSupported
 }
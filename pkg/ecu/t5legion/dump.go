@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
 	if !t.bootloaded {
 		if err := t.UploadBootLoader(ctx); err != nil {
 			return nil, err
@@ -28,7 +31,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 	length := 0x80000 - start
 	blockSize := 0x80 // defined in bootloader... keep it that way!
 
-	t.cfg.OnProgress(-float64(length))
+	phase := t.cfg.StartPhase(ecu.PhaseRead, int(length))
 	t.cfg.OnMessage("Dumping ECU")
 
 	buffer := make([]byte, length)
@@ -49,12 +52,17 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 		if blocksToSkip > 0 {
 			log.Println("Skipping", blocksToSkip, "blocks")
 			start += uint32(blocksToSkip * blockSize)
+			progress += blocksToSkip * blockSize
+			phase.Update(progress)
 			continue
 		}
 		copy(buffer[progress:], d)
 		start += uint32(blockSize)
 		progress += blockSize
-		t.cfg.OnProgress(float64(progress))
+		phase.Update(progress)
+	}
+	if err := t.verifyChecksum(ctx, getstartAddress(ecutype), buffer); err != nil {
+		return nil, err
 	}
 	t.cfg.OnMessage(fmt.Sprintf("Dumping ECU done, took %s", time.Since(startTime)))
 	return buffer, nil
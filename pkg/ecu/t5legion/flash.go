@@ -5,9 +5,12 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpFlash)()
 	if !t.bootloaded {
 		if err := t.UploadBootLoader(ctx); err != nil {
 			return err
@@ -26,7 +29,7 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 		return err
 	}
 
-	t.cfg.OnProgress(-float64(len(bin)))
+	phase := t.cfg.StartPhase(ecu.PhaseWrite, len(bin))
 	t.cfg.OnMessage("Flashing ECU")
 
 	r := bytes.NewReader(bin)
@@ -63,7 +66,7 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 				// send a bootloader frame whenever 7 bytes or a block of 0x80 bytes have been read from the BIN file
 				if i%7 == 6 || i == 0x80-1 {
 					if err := t.sendBootloaderDataCommand(ctx, data, 8); err != nil {
-						return fmt.Errorf("!!! FLASHing Failed !!! after: 0x%X bytes: %v", bytesRead, err)
+						return fmt.Errorf("!!! FLASHing Failed !!! after: 0x%X bytes: %w", bytesRead, err)
 
 					}
 				}
@@ -71,7 +74,11 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 		}
 		bytesRead += 0x80
 
-		t.cfg.OnProgress(float64(bytesRead))
+		phase.Update(int(bytesRead))
+	}
+
+	if err := t.verifyChecksum(ctx, start, bin[:bytesRead]); err != nil {
+		return err
 	}
 
 	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(startTime).Round(time.Millisecond).String()))
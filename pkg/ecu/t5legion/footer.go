@@ -6,8 +6,6 @@ import (
 	"strings"
 )
 
-//var ecuFooter []byte
-
 func (t *Client) GetECUFooter(ctx context.Context) ([]byte, error) {
 	//if !t.bootloaded {
 	//	if err := t.UploadBootLoader(ctx); err != nil {
@@ -2,14 +2,15 @@ package t5legion
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/model"
 )
 
 func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
+	defer t.cfg.Begin(ecu.OpInfo)()
 	if !t.bootloaded {
 		if err := t.UploadBootLoader(ctx); err != nil {
 			return nil, err
@@ -74,7 +75,7 @@ func (t *Client) printECUType(ctx context.Context) error {
 	case T55ECU:
 		t.cfg.OnMessage("This is a Trionic 5.5 ECU with 256 kB of FLASH")
 	default:
-		return errors.New("printECUType: unknown ECU")
+		return fmt.Errorf("printECUType: %w", ecu.ErrWrongECUType)
 	}
 	return nil
 }
@@ -122,7 +123,7 @@ func (t *Client) DetermineECU(ctx context.Context) (ECUType, error) {
 		case "060000":
 			return T52ECU, nil
 		default:
-			return UnknownECU, errors.New("!!! ERROR !!! This is a Trionic 5.2 ECU running an unknown firmware")
+			return UnknownECU, fmt.Errorf("!!! ERROR !!! This is a Trionic 5.2 ECU running an unknown firmware: %w", ecu.ErrWrongECUType)
 		}
 	case 256:
 		switch romoffset {
@@ -131,11 +132,11 @@ func (t *Client) DetermineECU(ctx context.Context) (ECUType, error) {
 		case "060000":
 			return T55AST52, nil
 		default:
-			return UnknownECU, errors.New("!!! ERROR !!! This is a Trionic 5.5 ECU running an unknown firmware")
+			return UnknownECU, fmt.Errorf("!!! ERROR !!! This is a Trionic 5.5 ECU running an unknown firmware: %w", ecu.ErrWrongECUType)
 		}
 	}
 
-	return UnknownECU, errors.New("!!! ERROR !!! this is a unknown ECU")
+	return UnknownECU, fmt.Errorf("!!! ERROR !!! this is a unknown ECU: %w", ecu.ErrWrongECUType)
 }
 
 var T5Headers = []model.Header{
@@ -12,10 +12,11 @@ import (
 
 func init() {
 	ecu.Register(&ecu.EcuInfo{
-		Name:    "Trionic 5 Legion",
-		NewFunc: New,
-		CANRate: 615.384,
-		Filter:  []uint32{0x00, 0x05, 0x06, 0x0C},
+		Name:         "Trionic 5 Legion",
+		NewFunc:      New,
+		CANRate:      615.384,
+		Filter:       []uint32{0x00, 0x05, 0x06, 0x0C},
+		Capabilities: ecu.CapInfo | ecu.CapDump | ecu.CapFlash | ecu.CapErase,
 	})
 }
 
@@ -50,6 +51,7 @@ type Client struct {
 	bootloaded     bool
 	//cb             model.ProgressCallback
 	cfg       *ecu.Config
+	chipTypes []byte
 	ecuFooter []byte
 }
 
@@ -62,22 +64,28 @@ func New(c *gocan.Client, cfg *ecu.Config) ecu.Client {
 	return t
 }
 
-var chipTypes []byte
+// invalidate forgets everything cached about the ECU, after a reset the
+// bootloader has to be uploaded again and the ECU might have been swapped
+func (t *Client) invalidate() {
+	t.bootloaded = false
+	t.chipTypes = nil
+	t.ecuFooter = nil
+}
 
 func (t *Client) GetChipTypes(ctx context.Context) ([]byte, error) {
-	if len(chipTypes) > 0 {
-		return chipTypes, nil
+	if len(t.chipTypes) > 0 {
+		return t.chipTypes, nil
 	}
 	frame := gocan.NewFrame(0x5, []byte{0xC9, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 150*time.Millisecond, 0xC)
 	if err != nil {
-		return nil, err
+		return nil, ecu.NoResponse(err)
 	}
 	if resp.Data[0] != 0xC9 || resp.Data[1] != 0x00 {
 		return nil, errors.New("invalid GetChipTypes response")
 	}
-	chipTypes = resp.Data[2:]
-	return chipTypes, nil
+	t.chipTypes = resp.Data[2:]
+	return t.chipTypes, nil
 }
 
 func (t *Client) ReadMemoryByAddress(ctx context.Context, address uint32) ([]byte, error) {
@@ -85,7 +93,7 @@ func (t *Client) ReadMemoryByAddress(ctx context.Context, address uint32) ([]byt
 	frame := gocan.NewFrame(0x5, p, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 150*time.Millisecond, 0xC)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read memory by address: %v", err)
+		return nil, fmt.Errorf("failed to read memory by address: %w", ecu.NoResponse(err))
 	}
 	data := resp.Data[2:]
 	reverse(data)
@@ -0,0 +1,112 @@
+package t5legion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+// newSimClient connects a client to e
+func newSimClient(t *testing.T, e *sim.T5) (*Client, context.Context) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	t.Cleanup(cancel)
+	return New(sim.Connect(t, e), sim.Quiet(nil)).(*Client), ctx
+}
+
+func TestDumpECU(t *testing.T) {
+	e, err := sim.NewT5(nil, 0, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e)
+
+	bin, err := tr.DumpECU(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bin, e.Image()) {
+		t.Error("dump differs from the flash")
+	}
+}
+
+func TestDumpECUDroppedFlowControl(t *testing.T) {
+	// the dump does not retry a read, a lost flow control has to end it
+	// instead of leaving a hole in the bin
+	e, err := sim.NewT5(nil, 0, sim.Faults{DropAckEvery: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e)
+
+	if _, err := tr.DumpECU(ctx); !errors.Is(err, ecu.ErrNoResponse) {
+		t.Fatalf("got %v, want %v", err, ecu.ErrNoResponse)
+	}
+}
+
+func TestEraseWaitsForFlash(t *testing.T) {
+	const eraseTime = 500 * time.Millisecond
+	image := make([]byte, 0x40000)
+	e, err := sim.NewT5(image, 0, sim.Faults{EraseTime: eraseTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e)
+	if err := tr.UploadBootLoader(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := tr.EraseECU(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < eraseTime {
+		t.Errorf("erase returned after %s, the flash takes %s", took, eraseTime)
+	}
+	if !bytes.Equal(e.Image(), bytes.Repeat([]byte{0xFF}, len(image))) {
+		t.Error("flash was not erased")
+	}
+}
+
+func TestChecksum32(t *testing.T) {
+	tests := []struct {
+		name string
+		bin  []byte
+		want uint32
+	}{
+		{name: "empty", want: 0},
+		{name: "blank T5.2", bin: bytes.Repeat([]byte{0xFF}, 0x20000), want: 0x01FE0000},
+		{name: "blank T5.5", bin: bytes.Repeat([]byte{0xFF}, 0x40000), want: 0x03FC0000},
+		// bytes are added unsigned, 0x80 does not subtract
+		{name: "mixed", bin: []byte{0x4E, 0x75, 0x80, 0x01}, want: 0x0144},
+	}
+	for _, tt := range tests {
+		if got := checksum32(tt.bin); got != tt.want {
+			t.Errorf("%s: got %08X, want %08X", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	e, err := sim.NewT5(nil, 0, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e)
+
+	bin := e.Image()
+	if err := tr.Verify(ctx, bin); err != nil {
+		t.Fatal(err)
+	}
+	changed := append([]byte(nil), bin...)
+	changed[0x100]++
+	var verr *ecu.VerifyError
+	if err := tr.Verify(ctx, changed); !errors.As(err, &verr) {
+		t.Fatalf("got %v, want a verify error", err)
+	}
+}
@@ -0,0 +1,60 @@
+package t5legion
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// Verify compares the checksum-32 of bin with the one the loader calculates
+// over the flash. The T5 loader has no partition MD5s so a mismatch is
+// reported for the whole flash
+func (t *Client) Verify(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpVerify)()
+	if !t.bootloaded {
+		if err := t.UploadBootLoader(ctx); err != nil {
+			return err
+		}
+	}
+	ecutype, err := t.DetermineECU(ctx)
+	if err != nil {
+		return err
+	}
+	start := getstartAddress(ecutype)
+	if len(bin) != int(0x80000-start) {
+		return fmt.Errorf("%w: bin is %d bytes, ECU flash is %d", ecu.ErrWrongECUType, len(bin), 0x80000-start)
+	}
+	return t.verifyChecksum(ctx, start, bin)
+}
+
+// verifyChecksum compares the loader checksum-32 of the flash from start with
+// bin
+func (t *Client) verifyChecksum(ctx context.Context, start uint32, bin []byte) error {
+	t.cfg.OnMessage("Comparing checksum-32")
+	resp, err := t.IDemand(ctx, GetCRC32, 0)
+	if err != nil {
+		return err
+	}
+	remote := binary.BigEndian.Uint32(resp)
+	local := checksum32(bin)
+	t.cfg.OnMessage(fmt.Sprintf("Remote checksum-32 : %08X", remote))
+	t.cfg.OnMessage(fmt.Sprintf("Local checksum-32  : %08X", local))
+	if remote != local {
+		return &ecu.VerifyError{Ranges: []ecu.Range{{Start: start, End: 0x80000}}}
+	}
+	return nil
+}
+
+// checksum32 is what GetCRC32 answers for a flash holding bin. Despite the
+// name the loader does not calculate a CRC, the handler at 0x548E in
+// LegionBootloader adds up the bytes from address 0 to the flash size into a
+// 32 bit sum, which is the whole flash as the ECU maps it
+func checksum32(bin []byte) uint32 {
+	var sum uint32
+	for _, b := range bin {
+		sum += uint32(b)
+	}
+	return sum
+}
@@ -1,14 +1,317 @@
 package t7
 
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// A Trionic 7 bin carries three checksums. The firmware length is the FE
+// footer field and both footer checksums cover the firmware from address 0:
+//
+//	FB  sum of every byte
+//	F2  sum of 32 bit words mixed with an xor table, newer bins only
+//
+// The firmware also holds a table of 16 areas, a 4 byte address and 2 byte
+// length each, followed by the 32 bit sum of the bytes in those areas. The
+// table has no fixed address. Like T7Suite it is found through the firmware
+// routine that sums the areas, which loads the table address into a3. A bin
+// where that routine is missing, or points at something that is not a table,
+// has its area checksum left alone
+//
+// The area sum is inside the firmware, so Fix corrects it before the footer
+// checksums
+
+// Checksum names one of the Trionic 7 checksums
+type Checksum string
+
+const (
+	ChecksumFB   Checksum = "FB"
+	ChecksumF2   Checksum = "F2"
+	ChecksumArea Checksum = "area table"
+)
+
+// ChecksumError lists the checksums that do not match the bin, it matches
+// ecu.ErrVerifyMismatch
+type ChecksumError struct {
+	Failed []Checksum
+}
+
+func (e *ChecksumError) Error() string {
+	names := make([]string, len(e.Failed))
+	for i, c := range e.Failed {
+		names[i] = string(c)
+	}
+	return "bad checksum: " + strings.Join(names, ", ")
+}
+
+func (e *ChecksumError) Unwrap() error {
+	return ecu.ErrVerifyMismatch
+}
+
+// ErrNoAreaTable is returned by Verify when the footer checksums match but
+// the area table could not be found, so the area checksum was not checked
+var ErrNoAreaTable = errors.New("checksum area table not found")
+
+const (
+	binSize         = 0x80000
+	checksumAreas   = 16
+	areaTableLength = checksumAreas*6 + 4
+)
+
 type checksumArea struct {
 	addr   uint32
 	length uint16
 }
 
-func VerifyChecksum(bin []byte) error {
-	var areas []checksumArea
-	for i := 0; i < 16; i++ {
-		areas = append(areas, checksumArea{})
+// Verify checks the checksums of bin, a *ChecksumError lists the ones that
+// are wrong. The F2 checksum is only checked if the footer has one. If the
+// area table can not be found and nothing else is wrong ErrNoAreaTable is
+// returned
+func Verify(bin []byte) error {
+	fwLength, err := firmwareLength(bin)
+	if err != nil {
+		return err
+	}
+	cerr := &ChecksumError{}
+	table, areas := findAreaTable(bin, fwLength)
+	if table >= 0 && binary.BigEndian.Uint32(bin[table+checksumAreas*6:]) != areaChecksum(bin, areas) {
+		cerr.Failed = append(cerr.Failed, ChecksumArea)
+	}
+	pos, ok, err := footerField(bin, 0xF2, 4)
+	if err != nil {
+		return err
+	}
+	if ok && footerInt(bin, pos) != checksumF2(bin[:fwLength]) {
+		cerr.Failed = append(cerr.Failed, ChecksumF2)
+	}
+	pos, ok, err = footerField(bin, 0xFB, 4)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: bin has no FB checksum", ecu.ErrWrongECUType)
 	}
+	if footerInt(bin, pos) != checksumFB(bin[:fwLength]) {
+		cerr.Failed = append(cerr.Failed, ChecksumFB)
+	}
+	if len(cerr.Failed) > 0 {
+		return cerr
+	}
+	if table < 0 {
+		return ErrNoAreaTable
+	}
+	return nil
+}
+
+// Fix returns a copy of bin with every checksum Verify checks corrected. A
+// bin without an area table only gets its footer checksums fixed
+func Fix(bin []byte) ([]byte, error) {
+	fwLength, err := firmwareLength(bin)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(bin))
+	copy(out, bin)
+
+	if table, areas := findAreaTable(out, fwLength); table >= 0 {
+		binary.BigEndian.PutUint32(out[table+checksumAreas*6:], areaChecksum(out, areas))
+	}
+	if err := fixFooterChecksums(out, fwLength); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// fixFooterChecksums updates the F2 and FB checksums in the footer of bin
+func fixFooterChecksums(bin []byte, fwLength int) error {
+	pos, ok, err := footerField(bin, 0xF2, 4)
+	if err != nil {
+		return err
+	}
+	if ok {
+		putFooterInt(bin, pos, checksumF2(bin[:fwLength]))
+	}
+	pos, ok, err = footerField(bin, 0xFB, 4)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: bin has no FB checksum", ecu.ErrWrongECUType)
+	}
+	putFooterInt(bin, pos, checksumFB(bin[:fwLength]))
 	return nil
 }
+
+// VerifyChecksum is the old name of Verify
+//
+// Deprecated: use Verify
+func VerifyChecksum(bin []byte) error {
+	return Verify(bin)
+}
+
+func firmwareLength(bin []byte) (int, error) {
+	if len(bin) != binSize {
+		return 0, fmt.Errorf("%w: bin is %d bytes, Trionic 7 flash is %d", ecu.ErrWrongECUType, len(bin), binSize)
+	}
+	pos, ok, err := footerField(bin, 0xFE, 4)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: bin has no firmware length in the footer", ecu.ErrWrongECUType)
+	}
+	fwLength := int(footerInt(bin, pos))
+	if fwLength <= 0 || fwLength > binSize-footerMaxSize {
+		return 0, fmt.Errorf("%w: firmware length 0x%X in the footer is out of range", ecu.ErrWrongECUType, fwLength)
+	}
+	return fwLength, nil
+}
+
+func checksumFB(fw []byte) uint32 {
+	var sum uint32
+	for _, b := range fw {
+		sum += uint32(b)
+	}
+	return sum
+}
+
+var f2XorTable = [8]uint32{0x81184224, 0x24421881, 0xc33c6666, 0x3cc3c3c3, 0x11882244, 0x18241824, 0x84211248, 0x12345678}
+
+func checksumF2(fw []byte) uint32 {
+	var sum uint32
+	x := 1
+	for pos := 0; pos+4 <= len(fw); pos += 4 {
+		sum += binary.BigEndian.Uint32(fw[pos:]) ^ f2XorTable[x]
+		x = (x + 1) % len(f2XorTable)
+	}
+	sum ^= 0x40314081
+	sum -= 0x7FEFDFD0
+	return sum
+}
+
+func areaChecksum(bin []byte, areas []checksumArea) uint32 {
+	var sum uint32
+	for _, a := range areas {
+		for _, b := range bin[a.addr : a.addr+uint32(a.length)] {
+			sum += uint32(b)
+		}
+	}
+	return sum
+}
+
+// areaSumCode is the start of the firmware routine that sums the checksum
+// areas, the search pattern T7Suite uses. Bytes where areaSumMask is 0 are
+// the addresses loaded into a3 and a5 and differ between bins
+var (
+	areaSumCode = []byte{
+		0x48, 0xE7, 0x00, 0x3C, // movem.l a2-a5,-(sp)
+		0x24, 0x7C, 0x00, 0xF0, 0x00, 0x00, // movea.l #$F00000,a2
+		0x26, 0x7C, 0x00, 0x00, 0x00, 0x00, // movea.l #table,a3
+		0x28, 0x7C, 0x00, 0xF0, 0x00, 0x00, // movea.l #$F00000,a4
+		0x2A, 0x7C, 0x00, 0x00, 0x00, 0x00, // movea.l #...,a5
+		0x7C, 0x00, // moveq #0,d6
+		0x7A, 0x00, // moveq #0,d5
+	}
+	areaSumMask = []byte{
+		1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1,
+		1, 1, 0, 0, 0, 0,
+		1, 1, 1, 1, 1, 1,
+		1, 1, 0, 0, 0, 0,
+		1, 1,
+		1, 1,
+	}
+)
+
+// areaTablePointer is where the table address sits in areaSumCode
+const areaTablePointer = 12
+
+// findAreaTable returns the offset of the checksum area table and its areas,
+// or -1 if there is none. The table is the one areaSumCode points at, it is
+// only used if the routine is found once and the table holds 16 non empty
+// areas in ascending order, inside the firmware and not covering the table
+// itself
+func findAreaTable(bin []byte, fwLength int) (int, []checksumArea) {
+	table := -1
+	for pos := 0; pos+len(areaSumCode) <= fwLength; pos += 2 {
+		if !matchMasked(bin[pos:], areaSumCode, areaSumMask) {
+			continue
+		}
+		if table >= 0 {
+			return -1, nil
+		}
+		table = int(binary.BigEndian.Uint32(bin[pos+areaTablePointer:]))
+	}
+	if table < 0 || table+areaTableLength > fwLength {
+		return -1, nil
+	}
+	areas := make([]checksumArea, checksumAreas)
+	if !parseAreaTable(bin[table:], areas, uint32(table), fwLength) {
+		return -1, nil
+	}
+	return table, areas
+}
+
+func matchMasked(b, pattern, mask []byte) bool {
+	for i := range pattern {
+		if mask[i] != 0 && b[i] != pattern[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func parseAreaTable(b []byte, areas []checksumArea, table uint32, fwLength int) bool {
+	var next uint32
+	for i := range areas {
+		a := checksumArea{
+			addr:   binary.BigEndian.Uint32(b[i*6:]),
+			length: binary.BigEndian.Uint16(b[i*6+4:]),
+		}
+		end := a.addr + uint32(a.length)
+		if a.length == 0 || a.addr < next || end > uint32(fwLength) {
+			return false
+		}
+		if a.addr < table+areaTableLength && end > table {
+			return false
+		}
+		areas[i] = a
+		next = end
+	}
+	return true
+}
+
+// footerField finds the data of field id in the footer at the end of bin,
+// see walkFooter. The returned position is the first data byte and the rest
+// follow downwards. Like Footer.Field the last one stored is used
+func footerField(bin []byte, id byte, length int) (int, bool, error) {
+	pos, found := 0, false
+	_, err := walkFooter(bin, func(addr int, fid byte, flength int) {
+		if fid == id && flength == length {
+			pos, found = addr-2, true
+		}
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return pos, found, nil
+}
+
+func footerInt(bin []byte, pos int) uint32 {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		v = v<<8 | uint32(bin[pos-i])
+	}
+	return v
+}
+
+func putFooterInt(bin []byte, pos int, v uint32) {
+	for i := 3; i >= 0; i-- {
+		bin[pos-i] = byte(v)
+		v >>= 8
+	}
+}
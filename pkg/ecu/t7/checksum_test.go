@@ -0,0 +1,203 @@
+package t7
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// No stock bins are available to the tests, the vectors below are worked out
+// by hand from the algorithms as T7Suite describes them
+
+func TestChecksumFB(t *testing.T) {
+	tests := []struct {
+		fw   []byte
+		want uint32
+	}{
+		{nil, 0},
+		{[]byte{0x01, 0x02, 0x03, 0xFF}, 0x105},
+		{make([]byte, 0x100), 0},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, 0x7F8},
+	}
+	for _, tt := range tests {
+		if got := checksumFB(tt.fw); got != tt.want {
+			t.Errorf("checksumFB(% X) = 0x%X, want 0x%X", tt.fw, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumF2(t *testing.T) {
+	tests := []struct {
+		fw   []byte
+		want uint32
+	}{
+		// 0 ^ 0x40314081 - 0x7FEFDFD0
+		{nil, 0xC04160B1},
+		// (1 ^ 0x24421881) + (2 ^ 0xC33C6666) = 0xE77E7EE4
+		{[]byte{0, 0, 0, 1, 0, 0, 0, 2}, 0x275F5E95},
+		// a trailing partial word is not summed
+		{[]byte{0, 0, 0, 1, 0, 0, 0, 2, 0xFF}, 0x275F5E95},
+	}
+	for _, tt := range tests {
+		if got := checksumF2(tt.fw); got != tt.want {
+			t.Errorf("checksumF2(% X) = 0x%X, want 0x%X", tt.fw, got, tt.want)
+		}
+	}
+}
+
+func TestAreaChecksum(t *testing.T) {
+	bin := make([]byte, 0x100)
+	for i := range bin {
+		bin[i] = byte(i)
+	}
+	areas := []checksumArea{{addr: 0x10, length: 4}, {addr: 0xF0, length: 0x10}}
+	// 0x10+0x11+0x12+0x13 + 0xF0+...+0xFF
+	if got := areaChecksum(bin, areas); got != 0x46+0xF78 {
+		t.Errorf("areaChecksum = 0x%X, want 0x%X", got, 0x46+0xF78)
+	}
+}
+
+// testFirmwareLength is the firmware length in the footer of testBin
+const testFirmwareLength = 0x2000
+
+// testAreaSumCode is where testBin puts the routine pointing at the table
+const testAreaSumCode = 0x1100
+
+// putAreaSumCode writes the area sum routine at pos, loading table into a3
+func putAreaSumCode(bin []byte, pos, table int) {
+	copy(bin[pos:], areaSumCode)
+	binary.BigEndian.PutUint32(bin[pos+areaTablePointer:], uint32(table))
+	binary.BigEndian.PutUint32(bin[pos+24:], 0x12345678)
+}
+
+// testBin returns a Trionic 7 bin with a firmware length, F2 and FB in the
+// footer and, if withTable is set, an area table at 0x1000 and the routine
+// pointing at it. Every checksum is correct
+func testBin(t *testing.T, withTable bool) []byte {
+	t.Helper()
+	bin := make([]byte, binSize)
+	for i := range bin {
+		bin[i] = 0xFF
+	}
+	for i := 0; i < testFirmwareLength; i++ {
+		bin[i] = byte(i * 13)
+	}
+	copy(bin, []byte{0xFF, 0xFF, 0xEF, 0xFC})
+	if withTable {
+		table := bin[0x1000:]
+		for i := 0; i < checksumAreas; i++ {
+			binary.BigEndian.PutUint32(table[i*6:], uint32(0x100+i*0x80))
+			binary.BigEndian.PutUint16(table[i*6+4:], 0x40)
+		}
+		putAreaSumCode(bin, testAreaSumCode, 0x1000)
+	}
+	f := &Footer{}
+	for _, field := range []struct {
+		id   byte
+		data []byte
+	}{
+		{0xFE, binary.BigEndian.AppendUint32(nil, testFirmwareLength)},
+		{0xF2, make([]byte, 4)},
+		{0xFB, make([]byte, 4)},
+	} {
+		if err := f.Set(field.id, field.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin, err := f.Apply(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withTable {
+		if bin, err = Fix(bin); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bin
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   bool
+		corrupt func(bin []byte)
+		want    []Checksum
+		noTable bool
+	}{
+		{name: "good", table: true},
+		// only the footer checksums cover bytes outside the areas
+		{name: "firmware byte", table: true, corrupt: func(bin []byte) { bin[0x50]++ }, want: []Checksum{ChecksumF2, ChecksumFB}},
+		{name: "area byte", table: true, corrupt: func(bin []byte) { bin[0x100]++ }, want: []Checksum{ChecksumArea, ChecksumF2, ChecksumFB}},
+		{name: "area sum", table: true, corrupt: func(bin []byte) { bin[0x1000+checksumAreas*6+3]++ }, want: []Checksum{ChecksumArea, ChecksumF2, ChecksumFB}},
+		{name: "no table", noTable: true},
+		{name: "no table, firmware byte", corrupt: func(bin []byte) { bin[0x50]++ }, want: []Checksum{ChecksumF2, ChecksumFB}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := testBin(t, tt.table)
+			if tt.corrupt != nil {
+				tt.corrupt(bin)
+			}
+			err := Verify(bin)
+			var cerr *ChecksumError
+			switch {
+			case tt.want != nil:
+				if !errors.As(err, &cerr) || !slices.Equal(cerr.Failed, tt.want) {
+					t.Fatalf("got %v, want bad %v", err, tt.want)
+				}
+			case tt.noTable:
+				if !errors.Is(err, ErrNoAreaTable) {
+					t.Fatalf("got %v, want ErrNoAreaTable", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			fixed, err := Fix(bin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Verify(fixed); err != nil && !(errors.Is(err, ErrNoAreaTable) && !tt.table) {
+				t.Errorf("after Fix: %v", err)
+			}
+		})
+	}
+}
+
+func TestFindAreaTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(bin []byte)
+		want   int
+	}{
+		{name: "pointed at", want: 0x1000},
+		// a table shaped block is not enough without the routine
+		{name: "no routine", change: func(bin []byte) { bin[testAreaSumCode] = 0x4E }, want: -1},
+		{name: "two routines", change: func(bin []byte) { putAreaSumCode(bin, 0x1200, 0x1000) }, want: -1},
+		{name: "points at code", change: func(bin []byte) { putAreaSumCode(bin, testAreaSumCode, 0x40) }, want: -1},
+		{name: "points past the firmware", change: func(bin []byte) { putAreaSumCode(bin, testAreaSumCode, testFirmwareLength) }, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := testBin(t, true)
+			if tt.change != nil {
+				tt.change(bin)
+			}
+			if got, _ := findAreaTable(bin, testFirmwareLength); got != tt.want {
+				t.Fatalf("got table 0x%X, want 0x%X", got, tt.want)
+			}
+			if tt.want >= 0 {
+				return
+			}
+			// Fix must not write an area sum anywhere without a table
+			fixed, err := Fix(bin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(fixed[:testFirmwareLength], bin[:testFirmwareLength]) {
+				t.Error("Fix changed the firmware")
+			}
+		})
+	}
+}
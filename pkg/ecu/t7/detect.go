@@ -0,0 +1,41 @@
+package t7
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/model"
+)
+
+// Probe looks for a Trionic 7 with the 0x220 data initialization and reads
+// the engine type and VIN if it answers
+func Probe(ctx context.Context, c *gocan.Client) ([]model.HeaderResult, error) {
+	t := &Client{c: c, defaultTimeout: 250 * time.Millisecond}
+	resp, err := c.SendAndWait(ctx, gocan.NewFrame(0x220, []byte{0x3F, 0x81, 0x00, 0x11, 0x02, 0x40, 0x00, 0x00}, gocan.ResponseRequired), t.defaultTimeout, 0x238)
+	if err != nil {
+		return nil, ecu.NoResponse(err)
+	}
+	if !bytes.Equal(resp.Data, []byte{0x40, 0xBF, 0x21, 0xC1, 0x00, 0x11, 0x02, 0x58}) {
+		return nil, fmt.Errorf("%w: unexpected data initialization response %X", ecu.ErrWrongECUType, resp.Data)
+	}
+	defer t.StopSession(ctx)
+
+	var out []model.HeaderResult
+	for _, h := range T7Headers {
+		if h.ID != 0x97 && h.ID != 0x90 {
+			continue
+		}
+		value, err := t.GetHeader(ctx, h.ID)
+		if err != nil {
+			// it answered the data initialization, that is enough
+			break
+		}
+		out = append(out, model.HeaderResult{Header: h, Value: strings.Trim(value, "\x00")})
+	}
+	return out, nil
+}
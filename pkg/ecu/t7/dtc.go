@@ -2,21 +2,118 @@ package t7
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/model"
 )
 
+// ReadDTC reads the stored trouble codes with KWP2000
+// ReadDiagnosticTroubleCodesByStatus. Each code comes as two bytes in the
+// usual P/C/B/U encoding followed by its status byte. A refusal from the ECU
+// is returned as an error wrapping ecu.NegativeResponse
 func (t *Client) ReadDTC(ctx context.Context) ([]model.DTC, error) {
-	//kw := kwp2000.New(t.c, 0x220, 0x238)
-	//if err := kw.StartSession(ctx); err != nil {
-	//	return nil, err
-	//}
-	//
-	//ok, err := kw.RequestSecurityAccess(ctx, false)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//log.Printf("%t", ok)
-
-	return nil, nil
+	if err := t.DataInitialization(ctx); err != nil {
+		return nil, err
+	}
+
+	// status 0x02 asks for stored codes, group 0xFF00 for all of them
+	resp, err := t.query(ctx, 0x18, 0x02, 0xFF, 0x00)
+	if err != nil {
+		return nil, fmt.Errorf("read DTC: %w", err)
+	}
+	if len(resp) < 2 || resp[0] != 0x58 {
+		return nil, fmt.Errorf("read DTC: invalid response %X", resp)
+	}
+	count := int(resp[1])
+	if len(resp) < 2+count*3 {
+		return nil, fmt.Errorf("read DTC: %d codes announced but only %d bytes received", count, len(resp)-2)
+	}
+
+	out := make([]model.DTC, 0, count)
+	for i := 0; i < count; i++ {
+		d := resp[2+i*3:]
+		out = append(out, model.DTC{
+			Code:   dtcCode(d[0], d[1]),
+			Status: d[2],
+		})
+	}
+	return out, nil
+}
+
+// ClearDTC clears all trouble codes with KWP2000 ClearDiagnosticInformation
+// and reads back what is still stored
+func (t *Client) ClearDTC(ctx context.Context) ([]model.DTC, error) {
+	if err := t.DataInitialization(ctx); err != nil {
+		return nil, err
+	}
+
+	// group 0xFF00 is every code
+	resp, err := t.query(ctx, 0x14, 0xFF, 0x00)
+	if err != nil {
+		return nil, fmt.Errorf("clear DTC: %w", err)
+	}
+	if len(resp) < 1 || resp[0] != 0x54 {
+		return nil, fmt.Errorf("clear DTC: invalid response %X", resp)
+	}
+	t.cfg.OnMessage("DTC's cleared")
+
+	return t.ReadDTC(ctx)
+}
+
+// dtcCode formats a two byte trouble code, the top two bits select the
+// system letter and the rest are the digits
+func dtcCode(hi, lo byte) string {
+	return fmt.Sprintf("%c%X%03X", "PCBU"[hi>>6], (hi>>4)&0x03, uint16(hi&0x0F)<<8|uint16(lo))
+}
+
+// query sends a single frame KWP request on 0x240 and collects the reply
+// from 0x258, acknowledging each frame
+func (t *Client) query(ctx context.Context, req ...byte) ([]byte, error) {
+	if len(req) > 5 {
+		return nil, errors.New("query: request does not fit in one frame")
+	}
+	data := make([]byte, 8)
+	data[0], data[1], data[2] = 0x40, 0xA1, byte(len(req))
+	copy(data[3:], req)
+
+	sub := t.c.Subscribe(ctx, 0x258)
+	defer sub.Close()
+
+	if err := t.c.Send(0x240, data, gocan.ResponseRequired); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	length := -1
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(t.defaultTimeout * 4):
+			return nil, ecu.ErrNoResponse
+		case f := <-sub.Chan():
+			if f.Data[0]&0x40 == 0x40 {
+				if err := checkNegative(f); err != nil {
+					t.Ack(f.Data[0], gocan.Outgoing)
+					return nil, err
+				}
+				length = int(f.Data[2])
+				out = append(out[:0], f.Data[3:8]...)
+			} else if length >= 0 {
+				out = append(out, f.Data[2:8]...)
+			}
+			if f.Data[0] == 0x80 || f.Data[0] == 0xC0 {
+				t.Ack(f.Data[0], gocan.Outgoing)
+				if length < 0 {
+					return nil, errors.New("query: reply ended before it started")
+				}
+				return out[:min(length, len(out))], nil
+			}
+			t.Ack(f.Data[0], gocan.Outgoing)
+		}
+	}
 }
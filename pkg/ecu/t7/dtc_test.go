@@ -0,0 +1,68 @@
+package t7
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/model"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestDTC(t *testing.T) {
+	e, err := sim.NewT7(nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := newSimClient(t, e, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	got, err := tr.ReadDTC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.DTC{
+		{Code: "P0105", Status: 0x60},
+		{Code: "P1651", Status: 0x20},
+		{Code: "U2103", Status: 0x60},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read got %v, want %v", got, want)
+	}
+
+	// the sim keeps codes that are still active after a clear
+	got, err = tr.ClearDTC(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []model.DTC{
+		{Code: "P0105", Status: 0x60},
+		{Code: "U2103", Status: 0x60},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clear got %v, want %v", got, want)
+	}
+}
+
+func TestDTCRefused(t *testing.T) {
+	e, err := sim.NewT7(nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.RefuseDTC = true
+	tr := newSimClient(t, e, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var nr *ecu.NegativeResponse
+	if _, err := tr.ReadDTC(ctx); !errors.As(err, &nr) || nr.Service != 0x18 || nr.Code != 0x22 {
+		t.Errorf("read got %v, want a 0x22 negative response to 0x18", err)
+	}
+	if _, err := tr.ClearDTC(ctx); !errors.As(err, &nr) || nr.Service != 0x14 || nr.Code != 0x22 {
+		t.Errorf("clear got %v, want a 0x22 negative response to 0x14", err)
+	}
+}
@@ -8,12 +8,14 @@ import (
 
 	"github.com/avast/retry-go/v4"
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
 	ok, err := t.KnockKnock(ctx)
 	if err != nil || !ok {
-		return nil, fmt.Errorf("failed to authenticate: %v", err)
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
 	}
 	bin, err := t.readECU(ctx, 0, 0x80000)
 	if err != nil {
@@ -26,7 +28,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 func (t *Client) readECU(ctx context.Context, addr, length int) ([]byte, error) {
 	//addr := 0
 	//length := 0x80000
-	t.cfg.OnProgress(-float64(length))
+	phase := t.cfg.StartPhase(ecu.PhaseRead, length)
 	t.cfg.OnMessage("Dumping ECU")
 
 	start := time.Now()
@@ -38,7 +40,7 @@ func (t *Client) readECU(ctx context.Context, addr, length int) ([]byte, error)
 	// }
 
 	for readPos < length {
-		t.cfg.OnProgress(float64(out.Len()))
+		phase.Update(out.Len())
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -69,7 +71,7 @@ func (t *Client) readECU(ctx context.Context, addr, length int) ([]byte, error)
 	if err := t.endDownloadMode(ctx); err != nil {
 		return nil, err
 	}
-	t.cfg.OnProgress(float64(out.Len()))
+	phase.Update(out.Len())
 	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).Round(time.Second).String()))
 
 	return out.Bytes(), nil
@@ -81,9 +83,12 @@ func (t *Client) readMemoryByAddress(ctx context.Context, address, length int) (
 	frame := gocan.NewFrame(0x240, []byte{0x00, 0xA1, byte((address >> 16) & 0xFF), byte((address >> 8) & 0xFF), byte(address & 0xFF), 0x00, 0x00, 0x00}, gocan.ResponseRequired)
 	f, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout*3, 0x258)
 	if err != nil {
-		return nil, err
+		return nil, ecu.NoResponse(err)
 	}
 	t.Ack(f.Data[0], gocan.Outgoing)
+	if err := checkNegative(f); err != nil {
+		return nil, fmt.Errorf("failed to jump to 0x%X: %w", address, err)
+	}
 	if f.Data[3] != 0x6C || f.Data[4] != 0xF0 {
 		return nil, fmt.Errorf("failed to jump to 0x%X got response: %s", address, f.String())
 	}
@@ -111,7 +116,7 @@ outer:
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		case <-time.After(t.defaultTimeout * 4):
-			return nil, fmt.Errorf("timeout")
+			return nil, fmt.Errorf("read data: %w", ecu.ErrNoResponse)
 
 		case f := <-sub.Chan():
 			if f.Data[0]&0x40 == 0x40 {
@@ -159,7 +164,7 @@ func (t *Client) endDownloadMode(ctx context.Context) error {
 	frameData := gocan.NewFrame(0x240, []byte{0x40, 0xA1, 0x01, 0x82, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frameData, t.defaultTimeout, 0x258)
 	if err != nil {
-		return fmt.Errorf("end download mode: %v", err)
+		return fmt.Errorf("end download mode: %w", ecu.NoResponse(err))
 	}
 	t.Ack(resp.Data[0], gocan.Outgoing)
 	return nil
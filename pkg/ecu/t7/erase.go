@@ -7,14 +7,16 @@ import (
 	"time"
 
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) EraseECU(ctx context.Context) error {
+	defer t.cfg.Begin(ecu.OpErase)()
 	data := make([]byte, 8)
 	eraseMsg := []byte{0x40, 0xA1, 0x02, 0x31, 0x52, 0x00, 0x00, 0x00}
 	confirmMsg := []byte{0x40, 0xA1, 0x01, 0x3E, 0x00, 0x00, 0x00, 0x00}
 
-	t.cfg.OnProgress(-float64(17))
+	phase := t.cfg.StartPhase(ecu.PhaseErase, 17)
 	t.cfg.OnMessage("Erasing FLASH")
 
 	progress := 0
@@ -25,13 +27,13 @@ func (t *Client) EraseECU(ctx context.Context) error {
 	for data[3] != 0x71 && i < 30 {
 		f, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, eraseMsg, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 		if err != nil {
-			return err
+			return ecu.NoResponse(err)
 		}
 		data = f.Data
 		t.Ack(data[0], gocan.Outgoing)
 		i++
 		progress++
-		t.cfg.OnProgress(float64(progress))
+		phase.Update(progress)
 		time.Sleep(250 * time.Millisecond)
 	}
 	if i > 10 {
@@ -45,13 +47,13 @@ func (t *Client) EraseECU(ctx context.Context) error {
 	for data[3] != 0x71 && i < 200 {
 		f, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, eraseMsg, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 		if err != nil {
-			return err
+			return ecu.NoResponse(err)
 		}
 		data = f.Data
 		t.Ack(data[0], gocan.Outgoing)
 		i++
 		progress++
-		t.cfg.OnProgress(float64(progress))
+		phase.Update(progress)
 		time.Sleep(250 * time.Millisecond)
 	}
 	// Check to see if erase operation lasted longer than 20 sec...
@@ -65,13 +67,13 @@ func (t *Client) EraseECU(ctx context.Context) error {
 		time.Sleep(250 * time.Millisecond)
 		f, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, confirmMsg, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 		if err != nil {
-			return err
+			return ecu.NoResponse(err)
 		}
 		data = f.Data
 		i++
 		progress++
 
-		t.cfg.OnProgress(float64(progress))
+		phase.Update(progress)
 
 	}
 	if i < 10 {
@@ -3,6 +3,9 @@ package t7
 import (
 	"errors"
 	"fmt"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 const (
@@ -115,3 +118,16 @@ func TranslateErrorCode(p byte) error {
 		return fmt.Errorf("unknown error %X", p)
 	}
 }
+
+// checkNegative returns an ecu.NegativeResponse if f is a negative response
+func checkNegative(f *gocan.CANFrame) error {
+	d := f.Data
+	if len(d) < 6 || d[3] != 0x7F {
+		return nil
+	}
+	nr := &ecu.NegativeResponse{Service: d[4], Code: d[5]}
+	if err := TranslateErrorCode(d[5]); err != nil {
+		nr.Description = err.Error()
+	}
+	return nr
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/avast/retry-go/v4"
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 func (t *Client) LoadBinFile(filename string) (int64, []byte, error) {
@@ -18,7 +19,7 @@ func (t *Client) LoadBinFile(filename string) (int64, []byte, error) {
 	readBytes := 0
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return 0, nil, fmt.Errorf("failed to read bin file: %v", err)
+		return 0, nil, fmt.Errorf("failed to read bin file: %w", err)
 	}
 	readBytes = len(data)
 
@@ -55,9 +56,32 @@ var t7offsets = []struct {
 
 // Flash the ECU
 func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpFlash)()
 	if bin[0] != 0xFF || bin[1] != 0xFF || bin[2] != 0xEF || bin[3] != 0xFC {
-		return fmt.Errorf("error: bin doesn't appear to be for a Trionic 7 ECU! (%02X%02X%02X%02X)",
-			bin[0], bin[1], bin[2], bin[3])
+		return fmt.Errorf("%w: bin doesn't appear to be for a Trionic 7 ECU! (%02X%02X%02X%02X)",
+			ecu.ErrWrongECUType, bin[0], bin[1], bin[2], bin[3])
+	}
+
+	// a bin with bad checksums leaves the car in limp mode, sort it out
+	// before anything is erased
+	err := Verify(bin)
+	var cerr *ChecksumError
+	if errors.As(err, &cerr) {
+		if !t.cfg.FixChecksums && !t.cfg.OnConfirm(fmt.Sprintf("Bin has a %s, fix it before flashing?", err)) {
+			return err
+		}
+		if bin, err = Fix(bin); err != nil {
+			return err
+		}
+		t.cfg.OnMessage("Checksums fixed")
+		err = Verify(bin)
+	}
+	if errors.Is(err, ErrNoAreaTable) {
+		if !t.cfg.OnConfirm("The checksum area table was not found, so the area checksum can not be checked. Flash anyway?") {
+			return err
+		}
+	} else if err != nil {
+		return err
 	}
 
 	if err := t.DataInitialization(ctx); err != nil {
@@ -66,7 +90,7 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 
 	ok, err := t.KnockKnock(ctx)
 	if err != nil || !ok {
-		return fmt.Errorf("failed to authenticate: %v", err)
+		return fmt.Errorf("failed to authenticate: %w", err)
 	}
 
 	if err := t.EraseECU(ctx); err != nil {
@@ -77,7 +101,7 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 	//	t.cfg.OnError(err)
 	//}
 
-	t.cfg.OnProgress(-float64(0x80000))
+	phase := t.cfg.StartPhase(ecu.PhaseWrite, 0x80000)
 	t.cfg.OnMessage("Flashing ECU")
 
 	start := time.Now()
@@ -100,7 +124,7 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 				}
 				binPos += writeBytes
 				left -= writeBytes
-				t.cfg.OnProgress(float64(binPos))
+				phase.Update(binPos)
 			}
 			return nil
 		},
@@ -119,10 +143,13 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 	}
 	end, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, []byte{0x40, 0xA1, 0x01, 0x37, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 	if err != nil {
-		return fmt.Errorf("error waiting for data transfer exit reply: %v", err)
+		return fmt.Errorf("error waiting for data transfer exit reply: %w", ecu.NoResponse(err))
 	}
 	// Send acknowledgement
 	t.Ack(end.Data[0], gocan.Outgoing)
+	if err := checkNegative(end); err != nil {
+		return fmt.Errorf("exit download mode failed: %w", err)
+	}
 
 	if end.Data[3] != 0x77 {
 		return errors.New("exit download mode failed")
@@ -147,10 +174,13 @@ func (t *Client) writeJump(ctx context.Context, offset, length int) error {
 
 	f, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, jumpMsg2, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 	if err != nil {
-		return fmt.Errorf("failed to enable request download #2")
+		return fmt.Errorf("failed to enable request download #2: %w", ecu.NoResponse(err))
 	}
 
 	t.Ack(f.Data[0], gocan.Outgoing)
+	if err := checkNegative(f); err != nil {
+		return fmt.Errorf("failed to enable request download: %w", err)
+	}
 
 	if f.Data[3] != 0x74 {
 		log.Println(f.String())
@@ -215,11 +245,14 @@ func (t *Client) writeRange(ctx context.Context, start, end int, bin []byte) err
 
 	resp, err := t.c.Recv(ctx, t.defaultTimeout, 0x258)
 	if err != nil {
-		return fmt.Errorf("error writing 0x%X - 0x%X was at pos 0x%X: %v", start, end, binPos, err)
+		return fmt.Errorf("error writing 0x%X - 0x%X was at pos 0x%X: %w", start, end, binPos, ecu.NoResponse(err))
 	}
 
 	// Send acknowledgement
 	t.Ack(resp.Data[0], gocan.Outgoing)
+	if err := checkNegative(resp); err != nil {
+		return fmt.Errorf("error writing 0x%X - 0x%X: %w", start, end, err)
+	}
 
 	if resp.Data[3] != 0x76 {
 		return fmt.Errorf("ECU did not confirm write")
@@ -0,0 +1,53 @@
+package t7
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestFlashECURefusesBadChecksums(t *testing.T) {
+	corrupt := testBin(t, true)
+	corrupt[0x50]++
+	tests := []struct {
+		name string
+		bin  []byte
+		want error
+	}{
+		{name: "bad checksum", bin: corrupt, want: ecu.ErrVerifyMismatch},
+		{name: "no area table", bin: testBin(t, false), want: ErrNoAreaTable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := sim.NewT7(nil, sim.Faults{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := e.Image()
+			var questions []string
+			tr := newSimClient(t, e, &ecu.Config{
+				OnConfirm: func(q string) bool {
+					questions = append(questions, q)
+					return false
+				},
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := tr.FlashECU(ctx, tt.bin); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if len(questions) != 1 {
+				t.Errorf("asked %q, want one question", questions)
+			}
+			if !bytes.Equal(e.Image(), before) {
+				t.Error("flash was changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package t7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// Footer field IDs, the names follow T7Suite
+const (
+	FieldVIN             byte = 0x90
+	FieldVehicleID       byte = 0x91
+	FieldImmoCode        byte = 0x92
+	FieldHardwareNr      byte = 0x93
+	FieldPartNumber      byte = 0x94
+	FieldSoftwareVersion byte = 0x95
+	FieldCarDescription  byte = 0x97
+	FieldEngineType      byte = 0x98
+	FieldTester          byte = 0x99
+	FieldDateModified    byte = 0x9A
+	FieldLastModifiedBy  byte = 0xFA
+)
+
+const (
+	// footerMaxSize is how far from the end of the bin a footer is read
+	footerMaxSize = 0x200
+	// footerFlashSize is the part of the footer FlashECU writes, a rewritten
+	// footer has to fit in it
+	footerFlashSize = 0x100
+)
+
+// TextField is a footer field holding text that can be edited
+type TextField struct {
+	ID     byte
+	Name   string
+	Length int // maximum length
+}
+
+// TextFields lists the text fields of the footer that can be edited
+var TextFields = []TextField{
+	{ID: FieldVIN, Name: "VIN", Length: 17},
+	{ID: FieldImmoCode, Name: "Immo code", Length: 15},
+	{ID: FieldPartNumber, Name: "Part number", Length: 7},
+	{ID: FieldSoftwareVersion, Name: "Software version", Length: 12},
+	{ID: FieldCarDescription, Name: "Car description", Length: 20},
+	{ID: FieldEngineType, Name: "Engine type", Length: 13},
+	{ID: FieldTester, Name: "Tester", Length: 6},
+	{ID: FieldDateModified, Name: "Date modified", Length: 4},
+}
+
+// LookupTextField finds a text field by name, ignoring case
+func LookupTextField(name string) (TextField, bool) {
+	for _, f := range TextFields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return TextField{}, false
+}
+
+// Footer is the field list stored backwards from the last byte of a Trionic 7
+// bin. Fields are kept in the order they are stored, the ones Footer does not
+// know are kept untouched
+type Footer struct {
+	fields []FileHeaderField
+	size   int // bytes used in the bin, 0 for a new footer
+}
+
+// ErrMalformedFooter is returned for a footer whose fields run past the start
+// of the bin or further than footerMaxSize from its end
+var ErrMalformedFooter = errors.New("malformed footer")
+
+// walkFooter calls fn for each field of the footer of bin, starting at the
+// last byte. Each field is a length byte at addr, an ID byte below it and
+// the data below that, a length of 0x00 or 0xFF ends the footer. The address
+// of that end marker is returned
+func walkFooter(bin []byte, fn func(addr int, id byte, length int)) (int, error) {
+	limit := max(len(bin)-footerMaxSize, 0)
+	addr := len(bin) - 1
+	for {
+		if addr < limit {
+			return 0, fmt.Errorf("%w: no end within 0x%X bytes", ErrMalformedFooter, footerMaxSize)
+		}
+		length := int(bin[addr])
+		if length == 0x00 || length == 0xFF {
+			return addr, nil
+		}
+		start := addr - 1 - length
+		if start < limit {
+			return 0, fmt.Errorf("%w: field at 0x%X is truncated", ErrMalformedFooter, addr)
+		}
+		fn(addr, bin[addr-1], length)
+		addr = start - 1
+	}
+}
+
+// ParseFooter reads the footer of bin, see walkFooter
+func ParseFooter(bin []byte) (*Footer, error) {
+	f := &Footer{}
+	end, err := walkFooter(bin, func(addr int, id byte, length int) {
+		data := make([]byte, length)
+		copy(data, bin[addr-1-length:addr-1])
+		f.fields = append(f.fields, FileHeaderField{
+			ID:     id,
+			Length: length,
+			Data:   reverse(data),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	f.size = len(bin) - 1 - end
+	return f, nil
+}
+
+// Fields returns a copy of every field in the order they are stored
+func (f *Footer) Fields() []FileHeaderField {
+	return append([]FileHeaderField(nil), f.fields...)
+}
+
+// Field returns the data of field id. Some bins have a field more than once,
+// the last one stored is the one the ECU uses
+func (f *Footer) Field(id byte) ([]byte, bool) {
+	for i := len(f.fields) - 1; i >= 0; i-- {
+		if f.fields[i].ID == id {
+			return f.fields[i].Data, true
+		}
+	}
+	return nil, false
+}
+
+// String returns field id as text, empty if the footer has none
+func (f *Footer) String(id byte) string {
+	data, _ := f.Field(id)
+	return string(data)
+}
+
+// Set replaces the data of every field id, the field is added if the footer
+// has none
+func (f *Footer) Set(id byte, data []byte) error {
+	if len(data) == 0 || len(data) >= 0xFF {
+		return fmt.Errorf("footer field 0x%02X: %d bytes does not fit", id, len(data))
+	}
+	field := FileHeaderField{ID: id, Length: len(data), Data: append([]byte(nil), data...)}
+	found := false
+	for i := range f.fields {
+		if f.fields[i].ID == id {
+			f.fields[i] = field
+			found = true
+		}
+	}
+	if !found {
+		f.fields = append(f.fields, field)
+	}
+	return nil
+}
+
+// SetString sets a text field, the value must be printable ASCII and no longer
+// than the field allows
+func (f *Footer) SetString(id byte, value string) error {
+	maxLength := 0xFE
+	name := fmt.Sprintf("footer field 0x%02X", id)
+	for _, tf := range TextFields {
+		if tf.ID == id {
+			maxLength, name = tf.Length, tf.Name
+		}
+	}
+	if len(value) > maxLength {
+		return fmt.Errorf("%s: %q is longer than %d characters", name, value, maxLength)
+	}
+	for _, c := range value {
+		if c < 0x20 || c > 0x7E {
+			return fmt.Errorf("%s: %q is not printable ASCII", name, value)
+		}
+	}
+	return f.Set(id, []byte(value))
+}
+
+// VIN returns the chassis number
+func (f *Footer) VIN() string {
+	return f.String(FieldVIN)
+}
+
+// SetVIN sets the chassis number
+func (f *Footer) SetVIN(vin string) error {
+	return f.SetString(FieldVIN, vin)
+}
+
+// Apply returns a copy of bin, the image the footer was read from, with the
+// footer rewritten. The FB and F2 checksums are recomputed when the footer
+// has a firmware length, the area table is inside the firmware and not
+// touched
+func (f *Footer) Apply(bin []byte) ([]byte, error) {
+	if len(bin) != binSize {
+		return nil, fmt.Errorf("%w: bin is %d bytes, Trionic 7 flash is %d", ecu.ErrWrongECUType, len(bin), binSize)
+	}
+	size := 0
+	for _, field := range f.fields {
+		size += 2 + field.Length
+	}
+	// the byte below the footer ends it
+	if size+1 > footerFlashSize {
+		return nil, fmt.Errorf("footer of %d bytes does not fit in the last 0x%X bytes of flash", size, footerFlashSize)
+	}
+
+	out := make([]byte, len(bin))
+	copy(out, bin)
+	for i := len(out) - max(f.size, size+1); i < len(out); i++ {
+		out[i] = 0xFF
+	}
+	addr := len(out) - 1
+	for _, field := range f.fields {
+		out[addr] = byte(field.Length)
+		out[addr-1] = field.ID
+		addr -= 2
+		for _, b := range field.Data {
+			out[addr] = b
+			addr--
+		}
+	}
+
+	// the firmware ends at least 0x200 bytes from the end, see firmwareLength
+	if fwLength, err := firmwareLength(out); err == nil {
+		if err := fixFooterChecksums(out, fwLength); err != nil {
+			return nil, err
+		}
+	}
+	f.size = size
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package t7
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// footerBytes stores fields the way they are found at the end of a bin, the
+// first field ends up in the last byte
+func footerBytes(fields ...FileHeaderField) []byte {
+	var out []byte
+	for _, f := range fields {
+		out = append(reverse(append([]byte(nil), f.Data...)), append([]byte{f.ID, byte(f.Length)}, out...)...)
+	}
+	return out
+}
+
+func field(id byte, data []byte) FileHeaderField {
+	return FileHeaderField{ID: id, Length: len(data), Data: data}
+}
+
+func TestParseFooter(t *testing.T) {
+	vin := field(FieldVIN, []byte("YS3FH41U581000001"))
+	tests := []struct {
+		name   string
+		bin    []byte
+		fields []FileHeaderField
+		err    error
+	}{
+		{name: "empty footer", bin: []byte{0x00, 0xFF}},
+		{name: "one field", bin: append([]byte{0xFF}, footerBytes(vin)...), fields: []FileHeaderField{vin}},
+		{name: "truncated field", bin: []byte{'A', 'B', FieldVIN, 0x11}, err: ErrMalformedFooter},
+		{name: "length byte at start", bin: []byte{0x01}, err: ErrMalformedFooter},
+		{name: "no end marker", bin: footerBytes(vin), err: ErrMalformedFooter},
+		{name: "longer than the footer area", bin: append(make([]byte, footerMaxSize), bytes.Repeat([]byte{0x01, 0x91, 0x01}, 0x100)...), err: ErrMalformedFooter},
+		{name: "empty bin", err: ErrMalformedFooter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFooter(tt.bin)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := f.Fields()
+			if len(got) != len(tt.fields) {
+				t.Fatalf("got %d fields, want %d", len(got), len(tt.fields))
+			}
+			for i := range got {
+				if got[i].ID != tt.fields[i].ID || !bytes.Equal(got[i].Data, tt.fields[i].Data) {
+					t.Errorf("field %d: got %02X %q, want %02X %q", i, got[i].ID, got[i].Data, tt.fields[i].ID, tt.fields[i].Data)
+				}
+			}
+		})
+	}
+}
+
+// fuzzBin returns a Trionic 7 sized bin ending with footer
+func fuzzBin(footer []byte) []byte {
+	bin := bytes.Repeat([]byte{0xFF}, binSize)
+	copy(bin[binSize-min(len(footer), binSize):], footer)
+	return bin
+}
+
+func FuzzParseFooter(f *testing.F) {
+	vin := field(FieldVIN, []byte("YS3FH41U581000001"))
+	fw := field(0xFE, binary.BigEndian.AppendUint32(nil, 0x1000))
+	fb := field(0xFB, []byte{0, 0, 0, 0})
+	f2 := field(0xF2, []byte{0, 0, 0, 0})
+
+	// truncated fields
+	f.Add([]byte{'A', 'B', FieldVIN, 0x11})
+	f.Add(footerBytes(vin)[4:])
+	// overlong footers, one field after another past the footer area
+	f.Add(bytes.Repeat([]byte{0x01, 0x91, 0x01}, 0x100))
+	f.Add(append([]byte{0xFF}, bytes.Repeat([]byte{0xFE}, 0x300)...))
+	// a length byte at the start of the bin
+	f.Add([]byte{0x01})
+	f.Add([]byte{0x00, 0x90, 0x01})
+	// duplicate and unknown IDs
+	f.Add(append([]byte{0xFF}, footerBytes(vin, field(FieldVIN, []byte("YS3EF48E0Y3000001")))...))
+	f.Add(append([]byte{0xFF}, footerBytes(field(0x42, []byte{1, 2, 3}), field(0x9C, []byte{1}))...))
+	// footers Apply rewrites with checksums
+	f.Add(append([]byte{0xFF}, footerBytes(fw, f2, fb, vin)...))
+	f.Add(append([]byte{0x00}, footerBytes(fw, fb)...))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// the raw input is a bin of its own, short ones have the footer
+		// run into the start
+		if footer, err := ParseFooter(data); err == nil {
+			if _, err := ParseFileHeader(data); err != nil && !errors.Is(err, ErrMalformedFooter) {
+				t.Errorf("ParseFileHeader: %v", err)
+			}
+			size := 0
+			for _, field := range footer.Fields() {
+				size += 2 + field.Length
+			}
+			if size != footer.size {
+				t.Errorf("footer size %d, fields take %d", footer.size, size)
+			}
+		} else if !errors.Is(err, ErrMalformedFooter) {
+			t.Fatalf("ParseFooter: %v", err)
+		}
+
+		bin := fuzzBin(data)
+		footer, err := ParseFooter(bin)
+		if err != nil {
+			if _, _, ferr := footerField(bin, 0xFE, 4); ferr == nil {
+				t.Errorf("footerField takes a footer ParseFooter refuses: %v", err)
+			}
+			return
+		}
+		if data, ok := footer.Field(0xFE); ok && len(data) == 4 {
+			pos, ok, err := footerField(bin, 0xFE, 4)
+			if err != nil || !ok || !bytes.Equal(data, reverse([]byte{bin[pos-3], bin[pos-2], bin[pos-1], bin[pos]})) {
+				t.Errorf("footerField disagrees with Footer.Field: %X %v %v", data, ok, err)
+			}
+		}
+
+		out, err := footer.Apply(bin)
+		if err != nil {
+			// too big for the flashed part of the footer
+			return
+		}
+		again, err := ParseFooter(out)
+		if err != nil {
+			t.Fatalf("reading back Apply: %v", err)
+		}
+		want, got := footer.Fields(), again.Fields()
+		if len(got) != len(want) {
+			t.Fatalf("got %d fields back, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if got[i].ID != want[i].ID || got[i].Length != want[i].Length {
+				t.Fatalf("field %d: got %02X/%d, want %02X/%d", i, got[i].ID, got[i].Length, want[i].ID, want[i].Length)
+			}
+			// Apply recomputes the checksums
+			if got[i].ID != 0xF2 && got[i].ID != 0xFB && !bytes.Equal(got[i].Data, want[i].Data) {
+				t.Fatalf("field %02X: got %X, want %X", got[i].ID, got[i].Data, want[i].Data)
+			}
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/model"
 )
 
@@ -22,6 +23,7 @@ var T7Headers = []model.Header{
 
 // Print out some Trionic7 info
 func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
+	defer t.cfg.Begin(ecu.OpInfo)()
 	if err := t.DataInitialization(ctx); err != nil {
 		return nil, err
 	}
@@ -32,7 +34,7 @@ func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
 	for _, d := range T7Headers {
 		h, err := t.GetHeader(ctx, byte(d.ID))
 		if err != nil {
-			return nil, fmt.Errorf("ECU info failed: %v", err)
+			return nil, fmt.Errorf("ECU info failed: %w", err)
 		}
 		res := model.HeaderResult{
 			Value: strings.Trim(h, "\x00"),
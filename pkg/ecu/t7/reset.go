@@ -3,8 +3,10 @@ package t7
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 )
 
 // Noop command to satisfy interface
@@ -13,13 +15,15 @@ func (t *Client) ResetECU2(ctx context.Context) error {
 }
 
 func (t *Client) ResetECU(ctx context.Context) error {
+	// the session is gone after a reset
+	defer func() { t.lastDataInitialization = time.Time{} }()
 	frame := gocan.NewFrame(0x240, []byte{0x40, 0xA1, 0x02, 0x11, 0x01}, gocan.ResponseRequired)
 	f, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout, 0x258)
 	if err != nil {
-		return err
+		return ecu.NoResponse(err)
 	}
-	if f.Data[3] == 0x7F {
-		return fmt.Errorf("failed to reset ECU: %s", TranslateErrorCode(f.Data[5]))
+	if err := checkNegative(f); err != nil {
+		return fmt.Errorf("failed to reset ECU: %w", err)
 	}
 	if f.Data[3] != 0x51 || f.Data[4] != 0x81 {
 		return fmt.Errorf("abnormal ecu reset response: %X", f.Data[3:])
@@ -11,14 +11,17 @@ import (
 	"github.com/avast/retry-go/v4"
 	"github.com/roffe/gocan"
 	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t7sec"
 )
 
 func init() {
 	ecu.Register(&ecu.EcuInfo{
-		Name:    "Trionic 7",
-		NewFunc: New,
-		CANRate: 500,
-		Filter:  []uint32{0x238, 0x258, 0x266},
+		Name:         "Trionic 7",
+		NewFunc:      New,
+		CANRate:      500,
+		Filter:       []uint32{0x238, 0x258, 0x266},
+		Capabilities: ecu.CapInfo | ecu.CapReadDTC | ecu.CapClearDTC | ecu.CapDump | ecu.CapFlash | ecu.CapErase,
+		Probe:        Probe,
 	})
 }
 
@@ -26,6 +29,8 @@ type Client struct {
 	c              *gocan.Client
 	defaultTimeout time.Duration
 	cfg            *ecu.Config
+
+	lastDataInitialization time.Time
 }
 
 func New(c *gocan.Client, cfg *ecu.Config) ecu.Client {
@@ -42,21 +47,18 @@ func (t *Client) Ack(val byte, typ gocan.CANFrameType) error {
 	return t.c.Send(0x266, []byte{0x40, 0xA1, 0x3F, val & 0xBF, 0x00, 0x00, 0x00, 0x00}, typ)
 }
 
-var lastDataInitialization time.Time
-
 func (t *Client) DataInitialization(ctx context.Context) error {
-	if !lastDataInitialization.IsZero() {
-		if time.Since(lastDataInitialization) < 8*time.Second {
+	if !t.lastDataInitialization.IsZero() {
+		if time.Since(t.lastDataInitialization) < 8*time.Second {
 			return nil
 		}
 	}
-	lastDataInitialization = time.Now()
 
 	err := retry.Do(
 		func() error {
 			resp, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x220, []byte{0x3F, 0x81, 0x00, 0x11, 0x02, 0x40, 0x00, 0x00}, gocan.ResponseRequired), t.defaultTimeout, 0x238)
 			if err != nil {
-				return fmt.Errorf("%v", err)
+				return ecu.NoResponse(err)
 			}
 			if !bytes.Equal(resp.Data, []byte{0x40, 0xBF, 0x21, 0xC1, 0x00, 0x11, 0x02, 0x58}) {
 				return fmt.Errorf("/!\\ Invalid data initialization response")
@@ -77,8 +79,9 @@ func (t *Client) DataInitialization(ctx context.Context) error {
 		retry.Delay(250*time.Millisecond),
 	)
 	if err != nil {
-		return fmt.Errorf("/!\\ Data initialization failed: %v", err)
+		return fmt.Errorf("/!\\ Data initialization failed: %w", err)
 	}
+	t.lastDataInitialization = time.Now()
 	return nil
 }
 
@@ -91,12 +94,12 @@ func (t *Client) GetHeader(ctx context.Context, id byte) (string, error) {
 		retry.Attempts(3),
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed getting header: %v", err)
+		return "", fmt.Errorf("failed getting header: %w", err)
 	}
 
 	select {
 	case <-ctx.Done():
-		return "", fmt.Errorf("failed getting header: %v", err)
+		return "", fmt.Errorf("failed getting header: %w", ctx.Err())
 	default:
 	}
 
@@ -105,7 +108,7 @@ func (t *Client) GetHeader(ctx context.Context, id byte) (string, error) {
 	for i := 0; i < 10; i++ {
 		f, err := t.c.Recv(ctx, t.defaultTimeout, 0x258)
 		if err != nil {
-			return "", err
+			return "", ecu.NoResponse(err)
 		}
 		if f.Data[0]&0x40 == 0x40 {
 			if int(f.Data[2]) > 2 {
@@ -142,9 +145,11 @@ func (t *Client) KnockKnock(ctx context.Context) (bool, error) {
 	if err := t.DataInitialization(ctx); err != nil {
 		return false, err
 	}
+	var lastErr error
 	for i := 0; i <= 4; i++ {
 		ok, err := t.letMeIn(ctx, i)
 		if err != nil {
+			lastErr = err
 			t.cfg.OnError(fmt.Errorf("/!\\ Failed to obtain security access: %v", err))
 			time.Sleep(3 * time.Second)
 			continue
@@ -155,7 +160,10 @@ func (t *Client) KnockKnock(ctx context.Context) (bool, error) {
 			return true, nil
 		}
 	}
-	return false, fmt.Errorf("/!\\ Failed to obtain security access")
+	if lastErr != nil {
+		return false, fmt.Errorf("%w: %w", ecu.ErrSecurityAccessDenied, lastErr)
+	}
+	return false, ecu.ErrSecurityAccessDenied
 }
 
 func (t *Client) letMeIn(ctx context.Context, method int) (bool, error) {
@@ -164,23 +172,29 @@ func (t *Client) letMeIn(ctx context.Context, method int) (bool, error) {
 
 	f, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, msg, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 	if err != nil {
-		return false, fmt.Errorf("request seed: %v", err)
+		return false, fmt.Errorf("request seed: %w", ecu.NoResponse(err))
 
 	}
 	t.Ack(f.Data[0], gocan.ResponseRequired)
+	if err := checkNegative(f); err != nil {
+		return false, fmt.Errorf("request seed: %w", err)
+	}
 
 	s := int(f.Data[5])<<8 | int(f.Data[6])
-	k := calcen(s, method)
+	k := t7sec.CalculateAccessKey(s, method)
 
 	msgReply[5] = byte(int(k) >> 8 & int(0xFF))
 	msgReply[6] = byte(k) & 0xFF
 
 	f2, err := t.c.SendAndWait(ctx, gocan.NewFrame(0x240, msgReply, gocan.ResponseRequired), t.defaultTimeout, 0x258)
 	if err != nil {
-		return false, fmt.Errorf("send seed: %v", err)
+		return false, fmt.Errorf("send seed: %w", ecu.NoResponse(err))
 
 	}
 	t.Ack(f2.Data[0], gocan.ResponseRequired)
+	if err := checkNegative(f2); err != nil {
+		return false, fmt.Errorf("send seed: %w", err)
+	}
 	if f2.Data[3] == 0x67 && f2.Data[5] == 0x34 {
 		return true, nil
 	} else {
@@ -189,30 +203,6 @@ func (t *Client) letMeIn(ctx context.Context, method int) (bool, error) {
 	}
 }
 
-func calcen(seed int, method int) int {
-	key := seed << 2
-	key &= 0xFFFF
-	switch method {
-	case 0:
-		key ^= 0x8142
-		key -= 0x2356
-	case 1:
-		key ^= 0x4081
-		key -= 0x1F6F
-	case 2:
-		key ^= 0x3DC
-		key -= 0x2356
-	case 3:
-		key ^= 0x3D7
-		key -= 0x2356
-	case 4:
-		key ^= 0x409
-		key -= 0x2356
-	}
-	key &= 0xFFFF
-	return key
-}
-
 func (t *Client) LetMeTry(ctx context.Context, key1, key2 int) bool {
 	msg := []byte{0x40, 0xA1, 0x02, 0x27, 0x05, 0x00, 0x00, 0x00}
 	msgReply := []byte{0x40, 0xA1, 0x04, 0x27, 0x06, 0x00, 0x00, 0x00}
@@ -257,5 +247,7 @@ func calcenCustom(seed int, key1, key2 int) int {
 }
 
 func (t *Client) StopSession(ctx context.Context) error {
+	// the next request has to initialize again
+	t.lastDataInitialization = time.Time{}
 	return t.c.Send(0x220, []byte{0x40, 0xA1, 0x02, 0x82, 0x00, 0x00, 0x00, 0x00}, gocan.ResponseRequired)
 }
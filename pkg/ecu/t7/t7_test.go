@@ -0,0 +1,136 @@
+package t7
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+// newSimClient connects a client to e, cfg may be nil
+func newSimClient(t *testing.T, e *sim.T7, cfg *ecu.Config) *Client {
+	t.Helper()
+	return New(sim.Connect(t, e), sim.Quiet(cfg)).(*Client)
+}
+
+func TestDataInitializationSession(t *testing.T) {
+	e, err := sim.NewT7(nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := newSimClient(t, e, nil)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tr.DataInitialization(cancelled); err == nil {
+		t.Fatal("data initialization succeeded on a cancelled context")
+	}
+	if !tr.lastDataInitialization.IsZero() {
+		t.Error("failed data initialization was remembered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tr.DataInitialization(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if tr.lastDataInitialization.IsZero() {
+		t.Error("data initialization was not remembered")
+	}
+
+	if err := tr.StopSession(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.lastDataInitialization.IsZero() {
+		t.Error("data initialization survived StopSession")
+	}
+}
+
+func TestKnockKnockRetriesBadKey(t *testing.T) {
+	e, err := sim.NewT7(nil, sim.Faults{BadKeys: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first method is refused by the fault, the second one is right
+	e.Method = 1
+	var errs []error
+	tr := newSimClient(t, e, nil)
+	tr.cfg.OnError = func(err error) { errs = append(errs, err) }
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ok, err := tr.KnockKnock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("got %t, %v", ok, err)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got %d failed attempts, want 1: %v", len(errs), errs)
+	}
+}
+
+func TestReadRetriesDroppedAck(t *testing.T) {
+	image := make([]byte, binSize)
+	for i := range image {
+		image[i] = byte(i * 7)
+	}
+	e, err := sim.NewT7(image, sim.Faults{DropAckEvery: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var retries int
+	tr := newSimClient(t, e, nil)
+	tr.cfg.OnMessage = func(msg string) {
+		if strings.HasPrefix(msg, "Failed to read memory") {
+			retries++
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if ok, err := tr.KnockKnock(ctx); err != nil || !ok {
+		t.Fatalf("knock knock: %t, %v", ok, err)
+	}
+	got, err := tr.readECU(ctx, 0, 0x400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, image[:0x400]) {
+		t.Error("read back differs from the image")
+	}
+	if retries == 0 {
+		t.Error("no read was retried")
+	}
+}
+
+func TestEraseWaitsForFlash(t *testing.T) {
+	const eraseTime = 600 * time.Millisecond
+	e, err := sim.NewT7(make([]byte, binSize), sim.Faults{EraseTime: eraseTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := newSimClient(t, e, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if ok, err := tr.KnockKnock(ctx); err != nil || !ok {
+		t.Fatalf("knock knock: %t, %v", ok, err)
+	}
+	start := time.Now()
+	if err := tr.EraseECU(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < eraseTime {
+		t.Errorf("erase returned after %s, the flash takes %s", took, eraseTime)
+	}
+	img := e.Image()
+	if img[0] != 0xFF || img[0x07AFFF] != 0xFF || img[binSize-1] != 0xFF {
+		t.Error("flash was not erased")
+	}
+	if img[0x07B000] != 0x00 {
+		t.Error("erase went past the erase areas")
+	}
+}
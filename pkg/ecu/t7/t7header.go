@@ -13,11 +13,12 @@ type FileHeaderField struct {
 	Data   []byte
 }
 
-func (f *FileHeaderField) SetString(v string) {
-	if len([]byte(v)) > int(f.Length) {
-		panic("to big")
+func (f *FileHeaderField) SetString(v string) error {
+	if len(v) > f.Length {
+		return fmt.Errorf("field 0x%02X: %q is longer than %d bytes", f.ID, v, f.Length)
 	}
 	f.Data = []byte(v)
+	return nil
 }
 
 func (f *FileHeaderField) String() string {
@@ -47,30 +48,30 @@ func (f *FileHeaderField) SetInt(n int) {
 	f.Data = b
 }
 
+// Int returns the first 4 data bytes as a big endian number, missing bytes
+// count as zero
 func (f *FileHeaderField) Int() int {
-	var val int
-	val |= int(f.Data[0])
-	val <<= 8
-	val |= int(f.Data[1])
-	val <<= 8
-	val |= int(f.Data[2])
-	val <<= 8
-	val |= int(f.Data[3])
-	return val
+	return f.number(4)
 }
 
+// SmallInt returns the first 2 data bytes as a big endian number
 func (f *FileHeaderField) SmallInt() int {
-	var val int
-	val |= int(f.Data[0])
-	val <<= 8
-	val |= int(f.Data[1])
-	return val
+	return f.number(2)
 }
 
 func (f *FileHeaderField) Byte() byte {
-	var b byte = 0
-	b |= f.Data[0]
-	return b
+	return byte(f.number(1))
+}
+
+func (f *FileHeaderField) number(size int) int {
+	var val int
+	for i := 0; i < size; i++ {
+		val <<= 8
+		if i < len(f.Data) {
+			val |= int(f.Data[i])
+		}
+	}
+	return val
 }
 
 func (f *FileHeaderField) Date() [5]byte {
@@ -86,12 +87,21 @@ func (f *FileHeaderField) Pretty() string {
 	return fmt.Sprintf("ID: %02X, Length: %d, Data: %q", f.ID, f.Length, f.Data)
 }
 
+// ReadField reads the field ending at the current position of file and
+// leaves the position on the length byte of the next one. A field with ID
+// 0xFF ends the footer
 func ReadField(file io.ReadWriteSeeker) (*FileHeaderField, error) {
 	sizeb := make([]byte, 1)
-	file.Read(sizeb)
-	file.Seek(-2, io.SeekCurrent)
+	if _, err := io.ReadFull(file, sizeb); err != nil {
+		return nil, fmt.Errorf("read field length: %w", err)
+	}
+	if _, err := file.Seek(-2, io.SeekCurrent); err != nil {
+		return nil, fmt.Errorf("read field ID: %w", err)
+	}
 	idb := make([]byte, 1)
-	file.Read(idb)
+	if _, err := io.ReadFull(file, idb); err != nil {
+		return nil, fmt.Errorf("read field ID: %w", err)
+	}
 	if idb[0] == 0xFF {
 		return &FileHeaderField{
 			ID:     0xFF,
@@ -100,9 +110,17 @@ func ReadField(file io.ReadWriteSeeker) (*FileHeaderField, error) {
 	}
 	size := int64(sizeb[0])
 	data := make([]byte, size)
-	file.Seek(-(size + 1), io.SeekCurrent)
-	file.Read(data)
-	file.Seek(-(size + 1), io.SeekCurrent)
+	if _, err := file.Seek(-(size + 1), io.SeekCurrent); err != nil {
+		return nil, fmt.Errorf("field 0x%02X is truncated: %w", idb[0], err)
+	}
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, fmt.Errorf("read field 0x%02X: %w", idb[0], err)
+	}
+	// the next length byte is below the data, at the start of the file
+	// there is none
+	if _, err := file.Seek(-(size + 1), io.SeekCurrent); err != nil {
+		return nil, fmt.Errorf("footer runs into the start of the file: %w", err)
+	}
 	fhf := &FileHeaderField{
 		ID:     idb[0],
 		Length: int(sizeb[0]),
@@ -147,27 +165,70 @@ type FileHeader struct {
 	checksumF2         int
 	checksumFB         int
 	fwLength           int
+
+	// fields parseField does not know, in the order they are stored
+	unknown []FileHeaderField
+	footer  *Footer
 }
 
 func (f *FileHeader) GetVin() string {
 	return f.chassisID
 }
 
-func (f *FileHeader) SetVin(vin string) {
+func (f *FileHeader) SetVin(vin string) error {
 	if len(vin) > 17 {
-		panic("VIN to long")
+		return fmt.Errorf("VIN %q is longer than 17 characters", vin)
 	}
 	f.chassisID = vin
+	return nil
+}
+
+// Fields returns every field of the footer in the order they are stored,
+// unknown ones included
+func (f *FileHeader) Fields() []FileHeaderField {
+	return f.footer.Fields()
 }
 
 func NewFileHeader(filename string, autoFixFooter bool) (*FileHeader, error) {
-	file, err := os.OpenFile(filename, os.O_RDWR, 0666)
+	bin, err := os.ReadFile(filename)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	fh, err := ParseFileHeader(bin)
+	if err != nil {
+		return nil, err
 	}
-	defer file.Close()
 
-	fh := new(FileHeader)
+	if (fh.chassisIDCounter > 1 || !fh.immoCodeDetected || !fh.chassisIDDetected) && autoFixFooter {
+		log.Println("bad footer detected & auto fix enabled")
+		footer := &Footer{
+			fields: fh.createNewFooter(fh.symbolTableMarkerDetected, fh.symbolTableChecksumDetected, fh.f2ChecksumDetected),
+			size:   fh.footer.size,
+		}
+		out, err := footer.Apply(bin)
+		if err != nil {
+			return nil, fmt.Errorf("fix footer: %w", err)
+		}
+		if err := os.WriteFile(filename, out, 0666); err != nil {
+			return nil, err
+		}
+		fh.footer = footer
+	}
+
+	log.Printf("%+v", fh)
+
+	return fh, nil
+}
+
+// ParseFileHeader reads the footer of bin. Fields it does not know are kept
+// as they are, a malformed footer is returned as an error
+func ParseFileHeader(bin []byte) (*FileHeader, error) {
+	footer, err := ParseFooter(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	fh := &FileHeader{footer: footer}
 
 	// init new values
 	fh.chassisID = "00000000000000000"
@@ -186,32 +247,26 @@ func NewFileHeader(filename string, autoFixFooter bool) (*FileHeader, error) {
 	fh.SetLastModifiedBy(0xFF, 4)
 	fh.testSerialNr = "050225"
 
-	if _, err := file.Seek(-1, io.SeekEnd); err != nil {
-		return nil, err
-	}
-
-	for {
-		fhf, err := ReadField(file)
-		if err != nil {
+	for i := range footer.fields {
+		if err := fh.parseField(&footer.fields[i]); err != nil {
 			return nil, err
 		}
-		if fhf.ID == 0xFF || fhf.ID == 0x00 {
-			break
-		}
-		fh.parseField(fhf)
 	}
-
-	if (fh.chassisIDCounter > 1 || !fh.immoCodeDetected || !fh.chassisIDDetected) && autoFixFooter {
-		log.Println("bad footer detected & auto fix enabled")
-		fh.clearFooter(file)
-		fh.createNewFooter(file, fh.symbolTableMarkerDetected, fh.symbolTableChecksumDetected, fh.f2ChecksumDetected)
-	}
-
-	log.Printf("%+v", fh)
-
 	return fh, nil
 }
-func (fh *FileHeader) parseField(fhf *FileHeaderField) {
+
+// numberFieldLengths are the sizes of the footer fields holding numbers,
+// anything else would be read as garbage
+var numberFieldLengths = map[byte]int{
+	0x9B: 4, 0x9C: 4, 0xF2: 4, 0xFB: 4, 0xFC: 4, 0xFD: 4, 0xFE: 4,
+	0xF5: 2, 0xF6: 2, 0xF7: 2, 0xF8: 2,
+	0xF9: 1, 0xFA: 5,
+}
+
+func (fh *FileHeader) parseField(fhf *FileHeaderField) error {
+	if want, ok := numberFieldLengths[fhf.ID]; ok && fhf.Length != want {
+		return fmt.Errorf("%w: field 0x%02X is %d bytes, expected %d", ErrMalformedFooter, fhf.ID, fhf.Length, want)
+	}
 	switch fhf.ID {
 	case 0x90:
 		fh.chassisID = fhf.String()
@@ -266,45 +321,14 @@ func (fh *FileHeader) parseField(fhf *FileHeaderField) {
 	case 0xFE:
 		fh.fwLength = fhf.Int()
 	default:
-		panic(fmt.Sprintf("Unknown ID: 0x%02X", fhf.ID))
+		fh.unknown = append(fh.unknown, *fhf)
 	}
+	return nil
 }
 
-func (f *FileHeader) clearFooter(file io.ReadWriteSeeker) {
-	log.Println("clear footer")
-	end, _ := file.Seek(0, io.SeekEnd)
-	file.Seek(0x07FE00, io.SeekStart)
-	length := end - 0x7FE00
-
-	piArea := make([]byte, length)
-	piArea[0] = 0xFF
-	for j := 1; j < len(piArea); j *= 2 {
-		copy(piArea[j:], piArea[:j])
-	}
-
-	n, err := file.Write(piArea)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	if n != int(length) {
-		log.Println("dgaf what i wrote")
-	}
-
-	/*
-		for i := 0; i < int(length); i++ {
-			_, err := file.Write([]byte{0xFF})
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				log.Fatal(err)
-			}
-		}
-	*/
-}
-
-func (f *FileHeader) createNewFooter(file io.ReadWriteSeeker, create9B bool, create9C bool, createF2 bool) {
+// createNewFooter returns the fields of a rebuilt footer, the unknown fields
+// go last
+func (f *FileHeader) createNewFooter(create9B bool, create9C bool, createF2 bool) []FileHeaderField {
 	log.Println("write footer")
 	headers := []FileHeaderField{
 		{
@@ -439,28 +463,7 @@ func (f *FileHeader) createNewFooter(file io.ReadWriteSeeker, create9B bool, cre
 	}
 
 	headers = append(headers, part2...)
-
-	file.Seek(-1, io.SeekEnd)
-	for _, h := range headers {
-		f.writeField(file, h)
-	}
-
-}
-
-func (fh *FileHeader) writeField(file io.ReadWriteSeeker, f FileHeaderField) {
-	log.Println(f.Pretty())
-	// Write length
-	file.Write([]byte{byte(f.Length)})
-	file.Seek(-2, io.SeekCurrent)
-
-	// Write ID
-	file.Write([]byte{f.ID})
-	file.Seek(-2, io.SeekCurrent) // Skip ID and length
-
-	for i := 0; i < f.Length; i++ {
-		file.Write([]byte{f.Data[i]})
-		file.Seek(-2, io.SeekCurrent)
-	}
+	return append(headers, f.unknown...)
 }
 
 func (fh *FileHeader) SetLastModifiedBy(value byte, pos int) {
@@ -0,0 +1,27 @@
+package t7sec
+
+// CalculateAccessKey returns the security access key for seed, method picks
+// the constants used by the different Trionic 7 software versions
+func CalculateAccessKey(seed int, method int) int {
+	key := seed << 2
+	key &= 0xFFFF
+	switch method {
+	case 0:
+		key ^= 0x8142
+		key -= 0x2356
+	case 1:
+		key ^= 0x4081
+		key -= 0x1F6F
+	case 2:
+		key ^= 0x3DC
+		key -= 0x2356
+	case 3:
+		key ^= 0x3D7
+		key -= 0x2356
+	case 4:
+		key ^= 0x409
+		key -= 0x2356
+	}
+	key &= 0xFFFF
+	return key
+}
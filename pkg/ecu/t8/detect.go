@@ -0,0 +1,37 @@
+package t8
+
+import (
+	"context"
+	"errors"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocan/pkg/gmlan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/model"
+)
+
+// Probe looks for a Trionic 8 by reading the VIN and hardware type over
+// GMLAN on 0x7E0, a negative response still means something answered
+func Probe(ctx context.Context, c *gocan.Client) ([]model.HeaderResult, error) {
+	gm := gmlan.New(c, 0x7e0, 0x5e8, 0x7e8)
+	var out []model.HeaderResult
+	for _, h := range T8Headers {
+		if h.ID != 0x90 && h.ID != 0x97 {
+			continue
+		}
+		var value string
+		err := ecu.GMLAN(ctx, c, []uint32{0x5e8, 0x7e8}, func() (err error) {
+			value, err = gm.ReadDataByIdentifierString(ctx, h.ID)
+			return err
+		})
+		if err != nil {
+			var nr *ecu.NegativeResponse
+			if errors.As(err, &nr) {
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, model.HeaderResult{Header: h, Value: value})
+	}
+	return out, nil
+}
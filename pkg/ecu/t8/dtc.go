@@ -5,18 +5,23 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/model"
 )
 
 func (t *Client) ReadDTC(ctx context.Context) ([]model.DTC, error) {
 	t.gm.TesterPresentNoResponseAllowed()
 
-	if err := t.gm.InitiateDiagnosticOperation(ctx, 0x02); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.InitiateDiagnosticOperation(ctx, 0x02) }); err != nil {
 		return nil, err
 	}
 
-	dtcs, err := t.gm.ReadDiagnosticInformationStatusOfDTCByStatusMask(ctx, 0x12)
-	if err != nil {
+	var dtcs [][]byte
+	if err := t.gmCall(ctx, func() (err error) {
+		dtcs, err = t.gm.ReadDiagnosticInformationStatusOfDTCByStatusMask(ctx, 0x12)
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -33,13 +38,52 @@ func (t *Client) ReadDTC(ctx context.Context) ([]model.DTC, error) {
 		})
 	}
 
-	if err := t.gm.ReturnToNormalMode(ctx); err != nil {
+	if err := t.returnToNormalMode(ctx); err != nil {
 		return out, err
 	}
 
 	return out, nil
 }
 
+// returnToNormalMode ends the diagnostic session ReadDTC starts. It is sent
+// here rather than with gmlan ReturnToNormalMode, which takes the positive
+// 01 60 reply for busy and fails every time
+func (t *Client) returnToNormalMode(ctx context.Context) error {
+	frame := gocan.NewFrame(t.variant.requestID, []byte{0x01, 0x20}, gocan.ResponseRequired)
+	resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout*2, t.variant.responseID())
+	if err != nil {
+		return fmt.Errorf("return to normal mode: %w", ecu.NoResponse(err))
+	}
+	if resp.Data[0] == 0x01 && resp.Data[1] == 0x60 {
+		return nil
+	}
+	if err := ecu.CheckGMLAN(resp); err != nil {
+		return fmt.Errorf("return to normal mode: %w", err)
+	}
+	return fmt.Errorf("return to normal mode: invalid response %X", resp.Data)
+}
+
+// ClearDTC clears the trouble codes with GMLAN ClearDiagnosticInformation
+// and reads back what is still stored
+func (t *Client) ClearDTC(ctx context.Context) ([]model.DTC, error) {
+	t.gm.TesterPresentNoResponseAllowed()
+
+	frame := gocan.NewFrame(t.variant.requestID, []byte{0x01, 0x04}, gocan.ResponseRequired)
+	resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout*4, t.variant.responseID())
+	if err != nil {
+		return nil, fmt.Errorf("clear DTC: %w", ecu.NoResponse(err))
+	}
+	if err := ecu.CheckGMLAN(resp); err != nil {
+		return nil, fmt.Errorf("clear DTC: %w", err)
+	}
+	if resp.Data[0] != 0x01 || resp.Data[1] != 0x44 {
+		return nil, fmt.Errorf("clear DTC: invalid response %X", resp.Data)
+	}
+	t.cfg.OnMessage("DTC's cleared")
+
+	return t.ReadDTC(ctx)
+}
+
 // How to read DTC codes
 //A7 A6    First DTC character
 //-- --    -------------------
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
-	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
 	"github.com/roffe/gocanflasher/pkg/t8legion"
 )
 
 func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
 	if err := t.legion.Bootstrap(ctx); err != nil {
 		return nil, err
 	}
@@ -19,7 +22,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 	t.cfg.OnMessage("Dumping ECU")
 	start := time.Now()
 
-	bin, err := t.legion.ReadFlash(ctx, t8legion.EcuByte_T8, 0x100000, false)
+	bin, err := t.legion.ReadFlash(ctx, t8legion.EcuByte_T8, t.variant.flashSize, false)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +39,111 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 	t.cfg.OnMessage(fmt.Sprintf("Local MD5  : %X", calculatedMD5))
 
 	if !bytes.Equal(ecuMD5bytes, calculatedMD5[:]) {
-		return nil, errors.New("md5 Verification failed")
+		return nil, fmt.Errorf("md5: %w", ecu.ErrVerifyMismatch)
+	}
+
+	t.cfg.OnMessage("Done, took: " + time.Since(start).String())
+
+	return bin, nil
+}
+
+// SRAM location in the main processor address space
+const (
+	sramStart = 0x100000
+	sramSize  = 0x8000
+)
+
+// DumpSRAM reads the SRAM from the running firmware with GMLAN
+// ReadMemoryByAddress under security access, the same way DumpStock reads
+// the flash. Nothing is uploaded, a Legion upload would overwrite part of the
+// SRAM and stop the firmware that keeps the adaptation values there
+func (t *Client) DumpSRAM(ctx context.Context) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
+	if err := t.RequestSecurityAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	t.cfg.OnMessage("Dumping SRAM")
+	start := time.Now()
+
+	bin := make([]byte, sramSize)
+	phase := t.cfg.StartPhase(ecu.PhaseRead, sramSize)
+	refused, err := t.readStock(ctx, bin, sramStart, phase)
+	if err != nil {
+		return nil, err
+	}
+	if len(refused) > 0 {
+		r := make([]string, len(refused))
+		for i, rr := range refused {
+			r[i] = rr.String()
+		}
+		return nil, fmt.Errorf("ECU refused to read SRAM %s", strings.Join(r, ", "))
+	}
+	phase.Done()
+
+	t.cfg.OnMessage("Done, took: " + time.Since(start).String())
+
+	return bin, nil
+}
+
+// DumpECUDiff dumps the ECU using base, an earlier dump of the same ECU, for
+// every partition whose MD5 still matches. Only the partitions that differ
+// are downloaded and the merged image is verified against the ECU
+func (t *Client) DumpECUDiff(ctx context.Context, base []byte) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
+	if err := t.checkSize("base", base); err != nil {
+		return nil, err
+	}
+	if err := t.legion.Bootstrap(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	t.cfg.OnMessage("Comparing MD5's with base")
+	phase := t.cfg.StartPhase(ecu.PhaseVerify, 9)
+	var partitions []int
+	var total int
+	for i := 1; i <= 9; i++ {
+		md5sum, err := t.legion.GetMD5(ctx, t8legion.GetTrionic8MD5, uint16(i))
+		if err != nil {
+			return nil, err
+		}
+		phase.Update(i)
+		if bytes.Equal(md5sum, t8util.GetPartitionMD5(base, 6, i)) {
+			continue
+		}
+		pstart, pend := t8util.PartitionRange(i)
+		total += int(pend - pstart)
+		partitions = append(partitions, i)
+	}
+
+	bin := make([]byte, len(base))
+	copy(bin, base)
+
+	if len(partitions) > 0 {
+		t.cfg.OnMessage(fmt.Sprintf("Downloading partitions %v", partitions))
+		phase = t.cfg.StartPhase(ecu.PhaseRead, total)
+		for _, p := range partitions {
+			pstart, pend := t8util.PartitionRange(p)
+			b, err := t.legion.ReadMemory(ctx, t8legion.EcuByte_T8, int(pstart), int(pend-pstart), phase)
+			if err != nil {
+				return nil, fmt.Errorf("partition %d: %w", p, err)
+			}
+			copy(bin[pstart:], b)
+		}
+		phase.Done()
+	} else {
+		t.cfg.OnMessage("ECU matches base, nothing to download")
+	}
+
+	t.cfg.OnMessage("Verifying md5..")
+	ecuMD5bytes, err := t.legion.IDemand(ctx, t8legion.GetTrionic8MD5, 0x00)
+	if err != nil {
+		return nil, err
+	}
+	calculatedMD5 := md5.Sum(bin)
+	if !bytes.Equal(ecuMD5bytes, calculatedMD5[:]) {
+		return nil, fmt.Errorf("md5: %w", ecu.ErrVerifyMismatch)
 	}
 
 	t.cfg.OnMessage("Done, took: " + time.Since(start).String())
@@ -0,0 +1,82 @@
+package t8
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+	"github.com/roffe/gocanflasher/pkg/t8legion"
+)
+
+// appPartitions is what EraseECU clears, the application area
+var appPartitions = []int{5, 6, 7, 8, 9}
+
+// EraseECU erases the application partitions through Legion, NVDM, HWIO and
+// boot are kept
+func (t *Client) EraseECU(ctx context.Context) error {
+	return t.ErasePartitions(ctx, appPartitions)
+}
+
+// ErasePartitions erases the given partitions, see t8util.Partitions, and
+// checks their MD5 afterwards to make sure they read blank. The boot
+// partition is refused unless Config.AllowBootWrite is set. Legion is exited
+// when done, also when the erase fails or ctx is cancelled
+func (t *Client) ErasePartitions(ctx context.Context, partitions []int) (err error) {
+	defer t.cfg.Begin(ecu.OpErase)()
+	if len(partitions) == 0 {
+		return errors.New("no partitions to erase")
+	}
+	var mask uint16
+	for _, p := range partitions {
+		if p < 1 || p > 9 {
+			return fmt.Errorf("invalid partition %d, valid are 1-9", p)
+		}
+		if p == 1 && !t.cfg.AllowBootWrite {
+			return errors.New("erasing the boot partition is not allowed")
+		}
+		mask |= 1 << (p - 1)
+	}
+
+	if err := t.legion.Bootstrap(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if exitErr := t.legion.Exit(context.WithoutCancel(ctx)); exitErr != nil {
+			t.cfg.OnError(exitErr)
+		}
+	}()
+	start := time.Now()
+
+	t.cfg.OnMessage(fmt.Sprintf("Erasing partitions %v", partitions))
+	phase := t.cfg.StartPhase(ecu.PhaseErase, 1)
+	if err := t.legion.ErasePartitions(ctx, t8legion.EcuByte_T8, mask); err != nil {
+		return err
+	}
+	phase.Done()
+
+	t.cfg.OnMessage("Verifying partitions are blank")
+	phase = t.cfg.StartPhase(ecu.PhaseVerify, len(partitions))
+	for n, p := range partitions {
+		md5sum, err := t.legion.GetMD5(ctx, t8legion.GetTrionic8MD5, uint16(p))
+		if err != nil {
+			return err
+		}
+		pstart, pend := t8util.PartitionRange(p)
+		blank := md5.Sum(bytes.Repeat([]byte{0xFF}, int(pend-pstart)))
+		if !bytes.Equal(md5sum, blank[:]) {
+			return fmt.Errorf("partition %d is not blank: %w", p, ecu.ErrVerifyMismatch)
+		}
+		phase.Update(n + 1)
+	}
+
+	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).Round(time.Second)))
+	return t.legion.Exit(ctx)
+}
@@ -0,0 +1,53 @@
+package t8
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestErasePartitions(t *testing.T) {
+	e, err := sim.NewT8(testImage(), nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e, nil)
+	if err := tr.ErasePartitions(ctx, []int{6}); err != nil {
+		t.Fatal(err)
+	}
+	start, end := t8util.PartitionRange(6)
+	if !bytes.Equal(e.Image()[start:end], bytes.Repeat([]byte{0xFF}, int(end-start))) {
+		t.Error("partition 6 was not erased")
+	}
+	if e.Running() {
+		t.Error("Legion is still running")
+	}
+}
+
+func TestErasePartitionsExitsOnError(t *testing.T) {
+	e, err := sim.NewT8(testImage(), nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e, nil)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// cancel once Legion is running and the erase is about to start
+	tr.cfg.OnMessage = func(msg string) {
+		if strings.HasPrefix(msg, "Erasing partitions") {
+			cancel()
+		}
+	}
+
+	if err := tr.ErasePartitions(ctx, []int{6}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if e.Running() {
+		t.Error("Legion was left running")
+	}
+}
@@ -0,0 +1,145 @@
+package t8
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+	"github.com/roffe/gocanflasher/pkg/sim"
+	"github.com/roffe/gocanflasher/pkg/t8legion"
+)
+
+func TestFlashECU(t *testing.T) {
+	image := testImage()
+	changed := func(partitions ...int) []byte {
+		bin := append([]byte(nil), image...)
+		for _, p := range partitions {
+			start, _ := t8util.PartitionRange(p)
+			bin[start+0x10] ^= 0xFF
+		}
+		return bin
+	}
+
+	tests := []struct {
+		name string
+		bin  []byte
+		// want is the flash afterwards and erased the partitions rewritten
+		want   []byte
+		erased string
+	}{
+		{name: "same bin", bin: image, want: image},
+		{name: "one partition", bin: changed(3), want: changed(3), erased: "Erasing partitions [3]"},
+		// boot writes are not allowed by default
+		{name: "boot partition", bin: changed(1, 5), want: changed(5), erased: "Erasing partitions [5]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := sim.NewT8(image, nil, sim.Faults{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, ctx := newSimClient(t, e, nil)
+			var messages []string
+			tr.cfg.OnMessage = func(msg string) { messages = append(messages, msg) }
+
+			if err := tr.FlashECU(ctx, tt.bin); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(e.Image(), tt.want) {
+				t.Error("flash differs from the expected image")
+			}
+			if tt.erased == "" {
+				if !slices.Contains(messages, "ECU already holds this bin, nothing to flash") {
+					t.Errorf("the unchanged bin was not skipped: %q", messages)
+				}
+				return
+			}
+			if !slices.Contains(messages, tt.erased) {
+				t.Errorf("no %q in %q", tt.erased, messages)
+			}
+		})
+	}
+}
+
+func TestFlashECUBadKey(t *testing.T) {
+	image := testImage()
+	bin := append([]byte(nil), image...)
+	start, _ := t8util.PartitionRange(4)
+	bin[start] ^= 0xFF
+
+	e, err := sim.NewT8(image, nil, sim.Faults{BadKeys: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e, nil)
+
+	if err := tr.FlashECU(ctx, bin); !errors.Is(err, ecu.ErrSecurityAccessDenied) {
+		t.Fatalf("got %v, want %v", err, ecu.ErrSecurityAccessDenied)
+	}
+	if !bytes.Equal(e.Image(), image) {
+		t.Error("flash was changed without security access")
+	}
+
+	// only the first key is refused
+	if err := tr.FlashECU(ctx, bin); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(e.Image(), bin) {
+		t.Error("flash differs from bin")
+	}
+}
+
+func TestFlashECUWaitsForErase(t *testing.T) {
+	const eraseTime = 500 * time.Millisecond
+	image := testImage()
+	bin := append([]byte(nil), image...)
+	start, _ := t8util.PartitionRange(2)
+	bin[start] ^= 0xFF
+
+	e, err := sim.NewT8(image, nil, sim.Faults{EraseTime: eraseTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e, nil)
+
+	begin := time.Now()
+	if err := tr.FlashECU(ctx, bin); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(begin); took < eraseTime {
+		t.Errorf("flash returned after %s, the erase takes %s", took, eraseTime)
+	}
+	if !bytes.Equal(e.Image(), bin) {
+		t.Error("flash differs from bin")
+	}
+}
+
+func TestReadMemoryRetriesDroppedFlowControl(t *testing.T) {
+	image := testImage()
+	e, err := sim.NewT8(image, nil, sim.Faults{DropAckEvery: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e, nil)
+	var retries int
+	tr.cfg.OnError = func(error) { retries++ }
+
+	if err := tr.legion.Bootstrap(ctx); err != nil {
+		t.Fatal(err)
+	}
+	retries = 0
+	got, err := tr.legion.ReadMemory(ctx, t8legion.EcuByte_T8, 0, 0x800, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, image[:0x800]) {
+		t.Error("read back differs from the image")
+	}
+	if retries == 0 {
+		t.Error("no read was retried")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/model"
 )
 
@@ -46,7 +47,8 @@ var T8Headers = []model.Header{
 }
 
 func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
-	t.cfg.OnProgress(-float64(len(T8Headers)))
+	defer t.cfg.Begin(ecu.OpInfo)()
+	phase := t.cfg.StartPhase(ecu.PhaseRead, len(T8Headers))
 	t.cfg.OnMessage("Fetching ECU info")
 
 	//time.Sleep(20 * time.Millisecond)
@@ -185,7 +187,7 @@ func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
 				out = append(out, res)
 			}
 		}
-		t.cfg.OnProgress(float64(i + 1))
+		phase.Update(i + 1)
 	}
 
 	return out, nil
@@ -240,7 +242,7 @@ func (t *Client) RequestECUInfo(ctx context.Context, pid byte) ([]byte, error) {
 }
 
 func (t *Client) SendAckMessageT8() {
-	if err := t.c.Send(0x7E0, []byte{0x30}, gocan.Outgoing); err != nil {
+	if err := t.c.Send(t.variant.requestID, []byte{0x30}, gocan.Outgoing); err != nil {
 		panic(err)
 	}
 }
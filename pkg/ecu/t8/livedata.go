@@ -0,0 +1,48 @@
+package t8
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/t8legion"
+)
+
+// Channels returns the analog inputs ReadLiveData can read
+func (t *Client) Channels() []string {
+	out := make([]string, len(t8legion.ADCChannels))
+	for i, ch := range t8legion.ADCChannels {
+		out[i] = ch.Name
+	}
+	return out
+}
+
+// ReadLiveData reads analog inputs through Legion, which is started on the
+// first call and left running until ResetECU
+func (t *Client) ReadLiveData(ctx context.Context, names ...string) ([]ecu.Reading, error) {
+	channels := t8legion.ADCChannels
+	if len(names) > 0 {
+		channels = make([]t8legion.ADCChannel, len(names))
+		for i, name := range names {
+			ch, ok := t8legion.LookupADCChannel(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown channel %q", name)
+			}
+			channels[i] = ch
+		}
+	}
+	if !t.legion.IsRunning() {
+		if err := t.legion.Bootstrap(ctx); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]ecu.Reading, len(channels))
+	for i, ch := range channels {
+		r, err := t.legion.ReadChannel(ctx, ch)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = r
+	}
+	return out, nil
+}
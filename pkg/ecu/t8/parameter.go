@@ -0,0 +1,149 @@
+package t8
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8sec"
+)
+
+// parameter binds an ecu.Parameter to the getter and setter using it
+type parameter struct {
+	ecu.Parameter
+	get func(t *Client, ctx context.Context) (string, error)
+	set func(t *Client, ctx context.Context, value string) error
+}
+
+var parameters = []parameter{
+	{
+		Parameter: ecu.Parameter{Name: "Top speed", PID: pidTopSpeed, Unit: "km/h", Kind: ecu.ParamInteger, Scale: 10, Min: 0, Max: 300, SecurityLevel: 0x01},
+		get: func(t *Client, ctx context.Context) (string, error) {
+			v, err := t.GetTopSpeed(ctx)
+			return strconv.Itoa(int(v)), err
+		},
+		set: func(t *Client, ctx context.Context, value string) error {
+			v, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return err
+			}
+			return t.SetTopSpeed(ctx, uint16(v))
+		},
+	},
+	{
+		Parameter: ecu.Parameter{Name: "RPM limiter", PID: pidRPMLimiter, Unit: "rpm", Kind: ecu.ParamInteger, Scale: 1, Min: 3000, Max: 8000, SecurityLevel: 0x01},
+		get: func(t *Client, ctx context.Context) (string, error) {
+			v, err := t.GetRPMLimiter(ctx)
+			return strconv.Itoa(int(v)), err
+		},
+		set: func(t *Client, ctx context.Context, value string) error {
+			v, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return err
+			}
+			return t.SetRPMLimit(ctx, uint16(v))
+		},
+	},
+	{
+		Parameter: ecu.Parameter{Name: "Oil quality", PID: pidOilQuality, Unit: "%", Scale: 256, Min: 0, Max: 100, SecurityLevel: 0x01},
+		get: func(t *Client, ctx context.Context) (string, error) {
+			v, err := t.GetOilQuality(ctx)
+			return strconv.FormatFloat(v, 'f', 2, 64), err
+		},
+		set: func(t *Client, ctx context.Context, value string) error {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			return t.SetOilQuality(ctx, v)
+		},
+	},
+	{
+		Parameter: ecu.Parameter{Name: "VIN", PID: pidVIN, Kind: ecu.ParamText, Length: 17, SecurityLevel: 0x01},
+		get: func(t *Client, ctx context.Context) (string, error) {
+			return t.GetVehicleVIN(ctx)
+		},
+		set: func(t *Client, ctx context.Context, value string) error {
+			return t.SetVehicleVIN(ctx, value)
+		},
+	},
+}
+
+func lookupParameter(name string) (parameter, error) {
+	for _, p := range parameters {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return parameter{}, fmt.Errorf("unknown parameter %q", name)
+}
+
+// Parameters lists what ReadParameter and WriteParameter accept
+func (t *Client) Parameters() []ecu.Parameter {
+	out := make([]ecu.Parameter, len(parameters))
+	for i, p := range parameters {
+		out[i] = p.Parameter
+	}
+	return out
+}
+
+func (t *Client) ReadParameter(ctx context.Context, name string) (string, error) {
+	p, err := lookupParameter(name)
+	if err != nil {
+		return "", err
+	}
+	var v string
+	if err := t.gmCall(ctx, func() (err error) {
+		v, err = p.get(t, ctx)
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("read %s: %w", p.Name, err)
+	}
+	return v, nil
+}
+
+// WriteParameter writes value after security access and reads it back. The
+// ECU stores scaled integers so the read back value is compared in raw units
+func (t *Client) WriteParameter(ctx context.Context, name, value string) error {
+	p, err := lookupParameter(name)
+	if err != nil {
+		return err
+	}
+	if err := p.Validate(value); err != nil {
+		return err
+	}
+	if err := ecu.SecurityAccess(t.gmCall(ctx, func() error {
+		return t.gm.RequestSecurityAccess(ctx, p.SecurityLevel, 0, t8sec.CalculateAccessKey)
+	})); err != nil {
+		return err
+	}
+	if err := t.gmCall(ctx, func() error { return p.set(t, ctx, value) }); err != nil {
+		return fmt.Errorf("write %s: %w", p.Name, err)
+	}
+	var got string
+	if err := t.gmCall(ctx, func() (err error) {
+		got, err = p.get(t, ctx)
+		return err
+	}); err != nil {
+		return fmt.Errorf("read back %s: %w", p.Name, err)
+	}
+	if !sameValue(p.Parameter, value, got) {
+		return fmt.Errorf("%s reads back as %q after writing %q: %w", p.Name, got, value, ecu.ErrVerifyMismatch)
+	}
+	t.cfg.OnMessage(fmt.Sprintf("%s set to %s %s", p.Name, got, p.Unit))
+	return nil
+}
+
+// sameValue compares two values of p as the ECU stores them
+func sameValue(p ecu.Parameter, a, b string) bool {
+	if p.Kind == ecu.ParamText {
+		return a == b
+	}
+	av, err1 := strconv.ParseFloat(a, 64)
+	bv, err2 := strconv.ParseFloat(b, 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return int64(av*p.Scale) == int64(bv*p.Scale)
+}
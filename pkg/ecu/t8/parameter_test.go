@@ -0,0 +1,30 @@
+package t8
+
+import (
+	"testing"
+
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestWriteParameter(t *testing.T) {
+	e, err := sim.NewT8(nil, nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ctx := newSimClient(t, e, nil)
+
+	if err := tr.WriteParameter(ctx, "Top speed", "240"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tr.ReadParameter(ctx, "Top speed"); err != nil || got != "240" {
+		t.Errorf("top speed reads %q, %v", got, err)
+	}
+
+	// refused before anything is written
+	if err := tr.WriteParameter(ctx, "RPM limiter", "6200.5"); err == nil {
+		t.Error("fractional rpm limit was accepted")
+	}
+	if got, _ := tr.ReadParameter(ctx, "RPM limiter"); got != "6200" {
+		t.Errorf("rpm limiter reads %q after a refused write", got)
+	}
+}
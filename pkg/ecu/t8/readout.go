@@ -0,0 +1,88 @@
+package t8
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// readOutBlockSize is the largest read the stock firmware answers
+const readOutBlockSize = 0x80
+
+// DumpStock reads the flash with GMLAN ReadMemoryByAddress under security
+// access, nothing is uploaded and programming mode is never entered. Blocks
+// the ECU refuses are left as 0xFF in the returned image and reported as
+// refused ranges
+func (t *Client) DumpStock(ctx context.Context) ([]byte, []ecu.Range, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
+	if err := t.RequestSecurityAccess(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	t.cfg.OnMessage("Reading ECU without bootloader")
+	start := time.Now()
+
+	bin := make([]byte, t.variant.flashSize)
+	for i := range bin {
+		bin[i] = 0xFF
+	}
+
+	phase := t.cfg.StartPhase(ecu.PhaseRead, len(bin))
+	refused, err := t.readStock(ctx, bin, 0, phase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(refused) == 1 && refused[0] == (ecu.Range{End: uint32(len(bin))}) {
+		return nil, refused, errors.New("ECU refused every read")
+	}
+	for _, r := range refused {
+		t.cfg.OnError(fmt.Errorf("ECU refused to read %s, left as 0xFF", r))
+	}
+
+	t.cfg.OnMessage("Done, took: " + time.Since(start).String())
+	return bin, refused, nil
+}
+
+// readStock fills buf with the memory from address on with
+// ReadMemoryByAddress and returns the ranges the ECU refused, which are left
+// untouched. Security access must be granted
+func (t *Client) readStock(ctx context.Context, buf []byte, address int, phase *ecu.ProgressReporter) ([]ecu.Range, error) {
+	var refused []ecu.Range
+	for pos := 0; pos < len(buf); pos += readOutBlockSize {
+		if pos%(readOutBlockSize*64) == 0 {
+			t.gm.TesterPresentNoResponseAllowed()
+		}
+		addr := address + pos
+		size := min(readOutBlockSize, len(buf)-pos)
+		var b []byte
+		err := t.gmCall(ctx, func() (err error) {
+			b, err = t.gm.ReadMemoryByAddress(ctx, uint32(addr), uint32(size))
+			return err
+		})
+		if err != nil {
+			var nr *ecu.NegativeResponse
+			if !errors.As(err, &nr) {
+				return nil, fmt.Errorf("read 0x%06X: %w", addr, err)
+			}
+			refused = addRange(refused, uint32(addr), uint32(addr+size))
+		} else {
+			copy(buf[pos:pos+size], b)
+		}
+		phase.Add(size)
+	}
+	return refused, nil
+}
+
+// addRange adds start-end to ranges, merging it with the last range if they
+// touch
+func addRange(ranges []ecu.Range, start, end uint32) []ecu.Range {
+	if n := len(ranges); n > 0 && ranges[n-1].End == start {
+		ranges[n-1].End = end
+		return ranges
+	}
+	return append(ranges, ecu.Range{Start: start, End: end})
+}
@@ -0,0 +1,28 @@
+package t8
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestDumpSRAM(t *testing.T) {
+	e, err := sim.NewT8(nil, nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := e.SRAM()
+	tr, ctx := newSimClient(t, e, nil)
+
+	got, err := tr.DumpSRAM(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("dump differs from the SRAM of the running firmware")
+	}
+	if e.Running() {
+		t.Error("a bootloader was started")
+	}
+}
@@ -0,0 +1,104 @@
+package t8
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+)
+
+// stockQuestion is asked through OnConfirm before every stock flash
+const stockQuestion = "Flashing through the factory bootloader is experimental and has not been checked against a real ECU. Write the application area this way?"
+
+// FlashStock programs the application area of bin through the factory
+// bootloader with GMLAN RequestDownload and TransferData, without uploading
+// Legion. It is meant for stock calibration updates and for ECUs that do not
+// accept the Legion upload. The factory loader has no checksum command, so
+// the written area is read back through the stock firmware, see DumpStock,
+// and compared with bin.
+//
+// This is experimental, the write sequence has only been run against the
+// simulator and not compared with a trace of a factory tool. It is only done
+// if the user agrees through OnConfirm, ecu.ErrAborted is returned otherwise
+func (t *Client) FlashStock(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpFlash)()
+	if err := t.checkSize("bin", bin); err != nil {
+		return err
+	}
+	if !t.cfg.OnConfirm(stockQuestion) {
+		return ecu.ErrAborted
+	}
+	if t.legion.IsRunning() {
+		if err := t.legion.Exit(ctx); err != nil {
+			return err
+		}
+	}
+	if err := t.legion.EnterProgrammingMode(ctx); err != nil {
+		return err
+	}
+	return t.flashStock(ctx, bin)
+}
+
+// flashStock writes the application area, the ECU must already be in
+// programming mode
+func (t *Client) flashStock(ctx context.Context, bin []byte) error {
+	start := time.Now()
+	first, _ := t8util.PartitionRange(appPartitions[0])
+	_, last := t8util.PartitionRange(appPartitions[len(appPartitions)-1])
+
+	t.cfg.OnMessage(fmt.Sprintf("Flashing 0x%06X-0x%06X through the factory bootloader", first, last))
+	phase := t.cfg.StartPhase(ecu.PhaseWrite, int(last-first))
+	if err := t.legion.StockDownload(ctx, int(first), bin[first:last], phase); err != nil {
+		return err
+	}
+	phase.Done()
+
+	// Legion exit is a plain return to normal mode, the factory bootloader
+	// answers it the same way
+	if err := t.legion.Exit(ctx); err != nil {
+		return err
+	}
+	if err := t.verifyStock(ctx, bin, int(first), int(last)); err != nil {
+		return err
+	}
+	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).Round(time.Second)))
+	return nil
+}
+
+// verifyStock reads first-last back with ReadMemoryByAddress and compares it
+// with bin. Blocks the ECU refuses to read back count as not verified
+func (t *Client) verifyStock(ctx context.Context, bin []byte, first, last int) error {
+	if err := t.RequestSecurityAccess(ctx); err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	t.cfg.OnMessage("Reading back to verify")
+	phase := t.cfg.StartPhase(ecu.PhaseVerify, last-first)
+	back := make([]byte, len(bin))
+	refused, err := t.readStock(ctx, back[first:last], first, phase)
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	if len(refused) > 0 {
+		r := make([]string, len(refused))
+		for i, rr := range refused {
+			r[i] = rr.String()
+		}
+		return fmt.Errorf("%w: ECU refused to read back %s", ecu.ErrVerifyMismatch, strings.Join(r, ", "))
+	}
+	verr := &ecu.VerifyError{}
+	for addr := first; addr < last; addr += readOutBlockSize {
+		end := min(addr+readOutBlockSize, last)
+		if !bytes.Equal(back[addr:end], bin[addr:end]) {
+			verr.Ranges = addRange(verr.Ranges, uint32(addr), uint32(end))
+		}
+	}
+	if len(verr.Ranges) > 0 {
+		return verr
+	}
+	t.cfg.OnMessage("Read back matches bin")
+	return nil
+}
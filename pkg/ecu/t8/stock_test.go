@@ -0,0 +1,70 @@
+package t8
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+	"github.com/roffe/gocanflasher/pkg/sim"
+	"github.com/roffe/gocanflasher/pkg/t8legion"
+)
+
+func TestFlashStock(t *testing.T) {
+	e, err := sim.NewT8(nil, nil, sim.Faults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := testImage()
+	first, _ := t8util.PartitionRange(appPartitions[0])
+
+	// the stock flash is experimental, it is not done unless confirmed
+	tr, ctx := newSimClient(t, e, nil)
+	if err := tr.FlashStock(ctx, bin); !errors.Is(err, ecu.ErrAborted) {
+		t.Fatalf("got %v, want %v", err, ecu.ErrAborted)
+	}
+	if bytes.Equal(e.Image()[first:], bin[first:]) {
+		t.Fatal("flashed without confirmation")
+	}
+
+	tr.cfg.OnConfirm = func(string) bool { return true }
+	if err := tr.FlashStock(ctx, bin); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(e.Image()[first:], bin[first:]) {
+		t.Error("application area differs from bin")
+	}
+}
+
+func TestFlashECUStockFallback(t *testing.T) {
+	for _, answer := range []bool{false, true} {
+		e, err := sim.NewT8(nil, nil, sim.Faults{RejectLoader: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		asked := false
+		tr, ctx := newSimClient(t, e, &ecu.Config{
+			OnConfirm: func(string) bool {
+				asked = true
+				return answer
+			},
+		})
+		bin := testImage()
+		err = tr.FlashECU(ctx, bin)
+		if !asked {
+			t.Errorf("answer %v: the fallback was not confirmed", answer)
+		}
+		first, _ := t8util.PartitionRange(appPartitions[0])
+		written := bytes.Equal(e.Image()[first:], bin[first:])
+		if answer {
+			if err != nil || !written {
+				t.Errorf("answer %v: got %v, written %v", answer, err, written)
+			}
+			continue
+		}
+		if !errors.Is(err, t8legion.ErrBootloaderUpload) || written {
+			t.Errorf("answer %v: got %v, written %v", answer, err, written)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package t8
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -18,10 +19,12 @@ import (
 
 func init() {
 	ecu.Register(&ecu.EcuInfo{
-		Name:    "Trionic 8",
-		NewFunc: New,
-		CANRate: 500,
-		Filter:  []uint32{0x5E8, 0x7E8},
+		Name:         trionic8.name,
+		NewFunc:      New,
+		CANRate:      500,
+		Filter:       trionic8.responseIDs,
+		Capabilities: ecu.CapInfo | ecu.CapReadDTC | ecu.CapClearDTC | ecu.CapDump | ecu.CapFlash | ecu.CapErase | ecu.CapSRAM | ecu.CapLiveData | ecu.CapParameterWrite,
+		Probe:        Probe,
 	})
 }
 
@@ -31,20 +34,64 @@ type Client struct {
 	legion         *t8legion.Client
 	gm             *gmlan.Client
 	cfg            *ecu.Config
+	variant        *variant
+}
+
+// variant holds the name, CAN IDs and flash size of the ECU
+type variant struct {
+	name        string
+	requestID   uint32
+	responseIDs []uint32
+	flashSize   int
+}
+
+// responseID is where the ECU answers diagnostic requests, the others carry
+// unsolicited frames
+func (v *variant) responseID() uint32 {
+	return v.responseIDs[len(v.responseIDs)-1]
+}
+
+var trionic8 = &variant{
+	name:        "Trionic 8",
+	requestID:   0x7E0,
+	responseIDs: []uint32{0x5E8, 0x7E8},
+	flashSize:   0x100000,
 }
 
 func New(c *gocan.Client, cfg *ecu.Config) ecu.Client {
+	return newClient(c, cfg, trionic8)
+}
+
+func newClient(c *gocan.Client, cfg *ecu.Config, v *variant) *Client {
 	t := &Client{
 		c:              c,
 		cfg:            ecu.LoadConfig(cfg),
 		defaultTimeout: 150 * time.Millisecond,
-		legion:         t8legion.New(c, cfg, 0x7e0, 0x7e8),
-		gm:             gmlan.New(c, 0x7e0, 0x5e8, 0x7e8),
+		legion:         t8legion.New(c, cfg, v.requestID, v.responseID()),
+		gm:             gmlan.New(c, v.requestID, v.responseIDs...),
+		variant:        v,
 	}
 	return t
 }
 
+// checkSize returns ErrWrongECUType unless bin is the size of the flash
+func (t *Client) checkSize(what string, bin []byte) error {
+	if len(bin) != t.variant.flashSize {
+		return fmt.Errorf("%w: %s is %d bytes, %s flash is %d", ecu.ErrWrongECUType, what, len(bin), t.variant.name, t.variant.flashSize)
+	}
+	return nil
+}
+
 func (t *Client) PrintECUInfo(ctx context.Context) error {
+	res, err := t.Info(ctx)
+	if err != nil {
+		return err
+	}
+	log.Println("----- ECU info ---------------")
+	for _, r := range res {
+		log.Println(r.Desc, r.Value)
+	}
+	log.Println("------------------------------")
 	return nil
 }
 
@@ -65,13 +112,34 @@ func (t *Client) ResetECU(ctx context.Context) error {
 	return nil
 }
 
+// FlashECU programs bin through Legion. Only partitions whose MD5 differs
+// from the ECU are erased and written, each is verified afterwards. The boot
+// partition is left alone unless Config.AllowBootWrite is set. If the ECU
+// refuses the Legion upload and the user agrees through OnConfirm, the
+// application area is written the experimental way FlashStock does instead
 func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
-	if err := t.legion.Bootstrap(ctx); err != nil {
+	defer t.cfg.Begin(ecu.OpFlash)()
+	if err := t.checkSize("bin", bin); err != nil {
 		return err
 	}
+	if err := t.legion.Bootstrap(ctx); err != nil {
+		if !errors.Is(err, t8legion.ErrBootloaderUpload) {
+			return err
+		}
+		// the ECU is still in programming mode with security access
+		t.cfg.OnError(err)
+		if !t.cfg.OnConfirm("The ECU refused the Legion upload. " + stockQuestion) {
+			return err
+		}
+		return t.flashStock(ctx, bin)
+	}
+	t.legion.CheckBattery(ctx)
+	start := time.Now()
+
 	t.cfg.OnMessage("Comparing MD5's for erase")
-	t.cfg.OnProgress(-9)
-	t.cfg.OnProgress(0)
+	phase := t.cfg.StartPhase(ecu.PhaseVerify, 9)
+	var mask uint16
+	var partitions []int
 	for i := 1; i <= 9; i++ {
 		lmd5 := t8util.GetPartitionMD5(bin, 6, i)
 		md5, err := t.legion.GetMD5(ctx, t8legion.GetTrionic8MD5, uint16(i))
@@ -80,19 +148,71 @@ func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
 		}
 		t.cfg.OnMessage(fmt.Sprintf("local partition   %d> %X", i, lmd5))
 		t.cfg.OnMessage(fmt.Sprintf("remote partition  %d> %X", i, md5))
-		t.cfg.OnProgress(float64(i))
+		phase.Update(i)
+		if bytes.Equal(lmd5, md5) {
+			continue
+		}
+		if i == 1 && !t.cfg.AllowBootWrite {
+			t.cfg.OnError(errors.New("boot partition differs, leaving it as is since boot writes are not allowed"))
+			continue
+		}
+		mask |= 1 << (i - 1)
+		partitions = append(partitions, i)
 	}
 
-	return nil
-}
+	if len(partitions) == 0 {
+		t.cfg.OnMessage("ECU already holds this bin, nothing to flash")
+		return t.legion.Exit(ctx)
+	}
 
-func (t *Client) EraseECU(ctx context.Context) error {
-	return nil
+	t.cfg.OnMessage(fmt.Sprintf("Erasing partitions %v", partitions))
+	phase = t.cfg.StartPhase(ecu.PhaseErase, 1)
+	if err := t.legion.ErasePartitions(ctx, t8legion.EcuByte_T8, mask); err != nil {
+		return err
+	}
+	phase.Done()
+
+	var total int
+	for _, p := range partitions {
+		pstart, pend := t8util.PartitionRange(p)
+		total += int(pend - pstart)
+	}
+	t.cfg.OnMessage("Flashing ECU")
+	phase = t.cfg.StartPhase(ecu.PhaseWrite, total)
+	for _, p := range partitions {
+		pstart, pend := t8util.PartitionRange(p)
+		if err := t.legion.WriteFlash(ctx, t8legion.EcuByte_T8, int(pstart), bin[pstart:pend], phase); err != nil {
+			return fmt.Errorf("partition %d: %w", p, err)
+		}
+	}
+
+	t.cfg.OnMessage("Verifying partitions")
+	phase = t.cfg.StartPhase(ecu.PhaseVerify, len(partitions))
+	for n, p := range partitions {
+		md5, err := t.legion.GetMD5(ctx, t8legion.GetTrionic8MD5, uint16(p))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(md5, t8util.GetPartitionMD5(bin, 6, p)) {
+			return fmt.Errorf("partition %d md5: %w", p, ecu.ErrVerifyMismatch)
+		}
+		phase.Update(n + 1)
+	}
+
+	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).Round(time.Second)))
+	return t.legion.Exit(ctx)
 }
 
 func (t *Client) RequestSecurityAccess(ctx context.Context) error {
 	log.Println("Requesting t8 security access")
-	return t.gm.RequestSecurityAccess(ctx, 0x01, 0, t8sec.CalculateAccessKey)
+	return ecu.SecurityAccess(t.gmCall(ctx, func() error {
+		return t.gm.RequestSecurityAccess(ctx, 0x01, 0, t8sec.CalculateAccessKey)
+	}))
+}
+
+// gmCall runs a gmlan request, see ecu.GMLAN
+func (t *Client) gmCall(ctx context.Context, fn func() error) error {
+	return ecu.GMLAN(ctx, t.c, t.variant.responseIDs, fn)
 }
 
 func (t *Client) GetOilQuality(ctx context.Context) (float64, error) {
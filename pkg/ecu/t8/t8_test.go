@@ -0,0 +1,27 @@
+package t8
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+// testImage returns a flash image without blank blocks
+func testImage() []byte {
+	bin := make([]byte, 0x100000)
+	for i := range bin {
+		bin[i] = byte(i*7 + i>>8)
+	}
+	return bin
+}
+
+// newSimClient connects a client to e, cfg may be nil
+func newSimClient(t *testing.T, e *sim.T8, cfg *ecu.Config) (*Client, context.Context) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	t.Cleanup(cancel)
+	return New(sim.Connect(t, e), sim.Quiet(cfg)).(*Client), ctx
+}
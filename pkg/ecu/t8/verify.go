@@ -0,0 +1,25 @@
+package t8
+
+import (
+	"context"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+	"github.com/roffe/gocanflasher/pkg/t8legion"
+)
+
+// Verify compares bin with the flash through the Legion checksum-32 and
+// partition MD5s, see t8legion.Client.Verify
+func (t *Client) Verify(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpVerify)()
+	if err := t.checkSize("bin", bin); err != nil {
+		return err
+	}
+	if err := t.legion.Bootstrap(ctx); err != nil {
+		return err
+	}
+	return t.legion.Verify(ctx, 0, bin, t8legion.GetTrionic8MD5, func(i int) (ecu.Range, []byte) {
+		start, end := t8util.PartitionRange(i)
+		return ecu.Range{Start: start, End: end}, t8util.GetPartitionMD5(bin, 6, i)
+	})
+}
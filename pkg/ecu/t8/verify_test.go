@@ -0,0 +1,56 @@
+package t8
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestVerify(t *testing.T) {
+	image := testImage()
+	changed := append([]byte(nil), image...)
+	start, end := t8util.PartitionRange(3)
+	changed[start+0x10] ^= 0xFF
+
+	tests := []struct {
+		name string
+		bin  []byte
+		// sum replaces the simulated Legion checksum-32
+		sum  func([]byte) uint32
+		want []ecu.Range
+	}{
+		{name: "match", bin: image},
+		{name: "partition differs", bin: changed, want: []ecu.Range{{Start: start, End: end}}},
+		// a checksum mismatch fails even when no partition MD5 differs
+		{name: "other sum", bin: image, sum: func([]byte) uint32 { return 0x12345678 }, want: []ecu.Range{{Start: 0, End: uint32(len(image))}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := sim.NewT8(image, nil, sim.Faults{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.sum != nil {
+				e.Checksum32 = tt.sum
+			}
+			tr, ctx := newSimClient(t, e, nil)
+			err = tr.Verify(ctx, tt.bin)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var verr *ecu.VerifyError
+			if !errors.As(err, &verr) {
+				t.Fatalf("got %v, want a verify error", err)
+			}
+			if len(verr.Ranges) != len(tt.want) || verr.Ranges[0] != tt.want[0] {
+				t.Errorf("got ranges %v, want %v", verr.Ranges, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
-	"errors"
 	"fmt"
 	"time"
 
@@ -17,10 +16,11 @@ import (
 
 func init() {
 	ecu.Register(&ecu.EcuInfo{
-		Name:    "Trionic 8 MCP",
-		NewFunc: New,
-		CANRate: 500,
-		Filter:  []uint32{0x7E8},
+		Name:         "Trionic 8 MCP",
+		NewFunc:      New,
+		CANRate:      500,
+		Filter:       []uint32{0x7E8},
+		Capabilities: ecu.CapInfo | ecu.CapDump | ecu.CapFlash,
 	})
 }
 
@@ -42,19 +42,15 @@ func New(c *gocan.Client, cfg *ecu.Config) ecu.Client {
 }
 
 func (t *Client) ReadDTC(ctx context.Context) ([]model.DTC, error) {
-	return nil, errors.New("MCP cannot do this")
+	return nil, ecu.ErrNotSupported
 }
 
 func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
-	if err := t.legion.Bootstrap(ctx); err != nil {
+	defer t.cfg.Begin(ecu.OpInfo)()
+	if err := t.startSecondary(ctx); err != nil {
 		return nil, err
 	}
 
-	_, err := t.legion.IDemand(ctx, t8legion.StartSecondaryBootloader, 0)
-	if err != nil {
-		return nil, errors.New("failed to start secondary bootloader")
-	}
-
 	ver, err := t.legion.GetMCPVersion(ctx)
 	if err != nil {
 		return nil, err
@@ -66,39 +62,122 @@ func (t *Client) Info(ctx context.Context) ([]model.HeaderResult, error) {
 }
 
 func (t *Client) PrintECUInfo(ctx context.Context) error {
-	return nil
+	_, err := t.Info(ctx)
+	return err
 }
 
+// FlashECU programs an MCP image through the Legion secondary bootloader.
+// Byte swapped images are swapped back before writing, only partitions whose
+// MD5 differs from the MCP are erased and written and each is verified
+// afterwards
 func (t *Client) FlashECU(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpFlash)()
+	if len(bin) != 0x40100 {
+		return fmt.Errorf("%w: bin is %d bytes, Trionic 8 MCP flash is %d", ecu.ErrWrongECUType, len(bin), 0x40100)
+	}
+	if t8util.MCPSwapped(bin) {
+		t.cfg.OnMessage("Bin is byte swapped, swapping it back")
+	}
+	native := t8util.MCPNative(bin)
 
-	if err := t.legion.Bootstrap(ctx); err != nil {
+	if err := t.startSecondary(ctx); err != nil {
 		return err
 	}
+	t.legion.CheckBattery(ctx)
+	start := time.Now()
+
 	t.cfg.OnMessage("Comparing MD5's for erase")
-	t.cfg.OnProgress(-9)
-	t.cfg.OnProgress(0)
+	phase := t.cfg.StartPhase(ecu.PhaseVerify, 9)
+	var mask uint16
+	var partitions []int
 	for i := 1; i <= 9; i++ {
-		lmd5 := t8util.GetPartitionMD5(bin, 6, i)
+		lmd5 := t8util.GetPartitionMD5(bin, 5, i)
 		md5, err := t.legion.GetMD5(ctx, t8legion.GetTrionic8MCPMD5, uint16(i))
 		if err != nil {
 			return err
 		}
 		t.cfg.OnMessage(fmt.Sprintf("local partition   %d> %X", i, lmd5))
 		t.cfg.OnMessage(fmt.Sprintf("remote partition  %d> %X", i, md5))
-		t.cfg.OnProgress(float64(i))
+		phase.Update(i)
+		if bytes.Equal(lmd5, md5) {
+			continue
+		}
+		mask |= 1 << (i - 1)
+		partitions = append(partitions, i)
 	}
 
-	return nil
+	if len(partitions) == 0 {
+		t.cfg.OnMessage("MCP already holds this bin, nothing to flash")
+		return t.legion.Exit(ctx)
+	}
+
+	t.cfg.OnMessage(fmt.Sprintf("Erasing partitions %v", partitions))
+	phase = t.cfg.StartPhase(ecu.PhaseErase, 1)
+	if err := t.legion.ErasePartitions(ctx, t8legion.EcuByte_MCP, mask); err != nil {
+		return err
+	}
+	phase.Done()
+
+	var total int
+	for _, p := range partitions {
+		pstart, pend := t8util.MCPPartitionRange(p)
+		total += int(pend - pstart)
+	}
+	t.cfg.OnMessage("Flashing MCP")
+	phase = t.cfg.StartPhase(ecu.PhaseWrite, total)
+	for _, p := range partitions {
+		pstart, pend := t8util.MCPPartitionRange(p)
+		if err := t.legion.WriteFlash(ctx, t8legion.EcuByte_MCP, int(pstart), native[pstart:pend], phase); err != nil {
+			return fmt.Errorf("partition %d: %w", p, err)
+		}
+	}
+
+	t.cfg.OnMessage("Verifying partitions")
+	phase = t.cfg.StartPhase(ecu.PhaseVerify, len(partitions))
+	for n, p := range partitions {
+		md5, err := t.legion.GetMD5(ctx, t8legion.GetTrionic8MCPMD5, uint16(p))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(md5, t8util.GetPartitionMD5(bin, 5, p)) {
+			return fmt.Errorf("partition %d md5: %w", p, ecu.ErrVerifyMismatch)
+		}
+		phase.Update(n + 1)
+	}
+
+	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).Round(time.Second)))
+	return t.legion.Exit(ctx)
 }
 
-func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
+// MarryMCP pairs the MCP with the main processor, needed after either board
+// has been replaced
+func (t *Client) MarryMCP(ctx context.Context) error {
+	if err := t.startSecondary(ctx); err != nil {
+		return err
+	}
+	t.cfg.OnMessage("Marrying MCP to main processor")
+	if _, err := t.legion.IDemand(ctx, t8legion.MarrySecondaryProcessor, 0); err != nil {
+		return fmt.Errorf("failed to marry secondary processor: %w", err)
+	}
+	t.cfg.OnMessage("MCP married")
+	return t.legion.Exit(ctx)
+}
+
+// startSecondary bootstraps Legion and starts the MCP bootloader through it
+func (t *Client) startSecondary(ctx context.Context) error {
 	if err := t.legion.Bootstrap(ctx); err != nil {
-		return nil, err
+		return err
+	}
+	if _, err := t.legion.IDemand(ctx, t8legion.StartSecondaryBootloader, 0); err != nil {
+		return fmt.Errorf("failed to start secondary bootloader: %w", err)
 	}
+	return nil
+}
 
-	_, err := t.legion.IDemand(ctx, t8legion.StartSecondaryBootloader, 0)
-	if err != nil {
-		return nil, errors.New("failed to start secondary bootloader")
+func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
+	defer t.cfg.Begin(ecu.OpDump)()
+	if err := t.startSecondary(ctx); err != nil {
+		return nil, err
 	}
 	t.cfg.OnMessage("Dumping MCP")
 
@@ -119,7 +198,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 	t.cfg.OnMessage(fmt.Sprintf("Local md5  : %X", calculatedMD5))
 
 	if !bytes.Equal(ecumd5bytes, calculatedMD5[:]) {
-		return nil, errors.New("md5 Verification failed")
+		return nil, fmt.Errorf("md5: %w", ecu.ErrVerifyMismatch)
 	}
 
 	t.cfg.OnMessage("Done, took: " + time.Since(start).String())
@@ -128,7 +207,7 @@ func (t *Client) DumpECU(ctx context.Context) ([]byte, error) {
 }
 
 func (t *Client) EraseECU(ctx context.Context) error {
-	return nil
+	return ecu.ErrNotSupported
 }
 
 func (t *Client) ResetECU(ctx context.Context) error {
@@ -139,3 +218,19 @@ func (t *Client) ResetECU(ctx context.Context) error {
 	}
 	return nil
 }
+
+// Verify compares bin with the MCP flash through the Legion checksum-32 and
+// partition MD5s, see t8legion.Client.Verify
+func (t *Client) Verify(ctx context.Context, bin []byte) error {
+	defer t.cfg.Begin(ecu.OpVerify)()
+	if len(bin) != 0x40100 {
+		return fmt.Errorf("%w: bin is %d bytes, Trionic 8 MCP flash is %d", ecu.ErrWrongECUType, len(bin), 0x40100)
+	}
+	if err := t.startSecondary(ctx); err != nil {
+		return err
+	}
+	return t.legion.Verify(ctx, 1, bin, t8legion.GetTrionic8MCPMD5, func(i int) (ecu.Range, []byte) {
+		start, end := t8util.MCPPartitionRange(i)
+		return ecu.Range{Start: start, End: end}, t8util.GetPartitionMD5(bin, 5, i)
+	})
+}
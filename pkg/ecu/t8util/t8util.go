@@ -39,7 +39,7 @@ func GetPartitionMD5(filebytes []byte, device, partition int) []byte {
 		}
 	case 5:
 		byteswapped = MCPSwapped(filebytes)
-		if partition > 0 && partition > 10 {
+		if partition > 0 && partition < 10 {
 			if partition == 9 {
 				start = 0x40000
 				end = 0x40100
@@ -86,3 +86,63 @@ func MCPSwapped(filebytes []byte) bool {
 	}
 	return false
 }
+
+// Partition is one of the Trionic 8 main flash partitions
+type Partition struct {
+	Number int
+	Name   string
+	Start  uint32
+	End    uint32
+}
+
+var t8partNames = []string{"Boot", "NVDM", "NVDM", "HWIO", "APP", "APP", "APP", "APP", "APP"}
+
+// Partitions returns Trionic 8 partition 1-9 in order
+func Partitions() []Partition {
+	out := make([]Partition, len(t8partNames))
+	for i, name := range t8partNames {
+		out[i] = Partition{
+			Number: i + 1,
+			Name:   name,
+			Start:  t8parts[i],
+			End:    t8parts[i+1],
+		}
+	}
+	return out
+}
+
+// PartitionRange returns the address range of Trionic 8 partition 1-9,
+// partition 1 being the boot partition
+func PartitionRange(partition int) (start, end uint32) {
+	if partition < 1 || partition > 9 {
+		return 0, 0
+	}
+	return t8parts[partition-1], t8parts[partition]
+}
+
+// MCPPartitionRange returns the address range of Trionic 8 MCP partition 1-9,
+// partition 9 being the shadow area
+func MCPPartitionRange(partition int) (start, end uint32) {
+	switch {
+	case partition == 9:
+		return 0x40000, 0x40100
+	case partition > 0 && partition < 9:
+		end = uint32(partition) << 15
+		return end - 0x8000, end
+	}
+	return 0, 0
+}
+
+// MCPNative returns the MCP image in the byte order of the MCP flash,
+// swapped images are returned as a swapped copy
+func MCPNative(filebytes []byte) []byte {
+	out := make([]byte, len(filebytes))
+	if !MCPSwapped(filebytes) {
+		copy(out, filebytes)
+		return out
+	}
+	for i := 0; i+1 < len(filebytes); i += 2 {
+		out[i], out[i+1] = filebytes[i+1], filebytes[i]
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package ecu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Verifier is implemented by clients that can compare a bin with the ECU
+// without reading the whole flash back
+type Verifier interface {
+	// Verify returns nil if the ECU holds bin, a *VerifyError if it does not
+	Verify(ctx context.Context, bin []byte) error
+}
+
+// Range is an address range, End is exclusive
+type Range struct {
+	Start uint32
+	End   uint32
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("0x%06X-0x%06X", r.Start, r.End)
+}
+
+// VerifyError lists the ranges that differ between a bin and the ECU, it
+// matches ErrVerifyMismatch
+type VerifyError struct {
+	Ranges []Range
+}
+
+func (e *VerifyError) Error() string {
+	if len(e.Ranges) == 0 {
+		return ErrVerifyMismatch.Error()
+	}
+	r := make([]string, len(e.Ranges))
+	for i, rr := range e.Ranges {
+		r[i] = rr.String()
+	}
+	return fmt.Sprintf("%s, differs in %s", ErrVerifyMismatch, strings.Join(r, ", "))
+}
+
+func (e *VerifyError) Unwrap() error {
+	return ErrVerifyMismatch
+}
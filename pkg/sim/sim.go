@@ -0,0 +1,262 @@
+// Package sim contains in-process ECU simulators that plug in as gocan adapters,
+// making it possible to exercise the ecu packages without a car on the bench.
+//
+// Simulators are registered as adapters, select one with gocan.NewAdapter and
+// pass settings through gocan.AdapterConfig.AdditionalConfig:
+//
+//	image          path to a flash image loaded at open, blank flash if unset
+//	delay          response delay, for example 500us
+//	drop_ack_every ignore every n:th acknowledgement from the tester
+//	bad_keys       reject the first n security access keys
+//	erase_time     how long a flash erase takes
+//	reject_loader  Trionic 8, refuse bootloader uploads to SRAM when true
+//	key_method     Trionic 7, t7sec method the key is checked against
+//	chip_id        Trionic 5, flash chip device id reported by 0xC9, for example 0xB8
+//	mcp_image      Trionic 8, path to an MCP flash image, blank MCP flash if unset
+package sim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/roffe/gocan"
+)
+
+// Faults that can be injected to exercise the retry logic in the ecu packages
+type Faults struct {
+	DropAckEvery int           // ignore every n:th ack frame from the tester
+	BadKeys      int           // reject the first n security access keys no matter what
+	EraseTime    time.Duration // time a flash erase takes before it is reported done
+	RejectLoader bool          // refuse bootloader uploads to SRAM
+}
+
+// dropAck returns true if the n:th ack should be ignored
+func (f *Faults) dropAck(n int) bool {
+	return f.DropAckEvery > 0 && n%f.DropAckEvery == 0
+}
+
+// ECU is a simulated control unit fed with every frame sent by the tester
+type ECU interface {
+	Handle(a *Adapter, f *gocan.CANFrame)
+}
+
+// Adapter is a gocan.Adapter connecting a gocan.Client to a simulated ECU
+type Adapter struct {
+	name  string
+	cfg   *gocan.AdapterConfig
+	ecu   ECU
+	delay time.Duration
+
+	filter map[uint32]struct{}
+
+	sendChan, recvChan chan *gocan.CANFrame
+	errChan            chan error
+	evtChan            chan gocan.Event
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewAdapter creates an adapter for the given ECU, frames not listed in
+// cfg.CANFilter are not delivered just like on a real adapter
+func NewAdapter(name string, ecu ECU, cfg *gocan.AdapterConfig) *Adapter {
+	if cfg == nil {
+		cfg = &gocan.AdapterConfig{}
+	}
+	a := &Adapter{
+		name:      name,
+		cfg:       cfg,
+		ecu:       ecu,
+		delay:     500 * time.Microsecond,
+		filter:    make(map[uint32]struct{}),
+		sendChan:  make(chan *gocan.CANFrame, 40),
+		recvChan:  make(chan *gocan.CANFrame, 1024),
+		errChan:   make(chan error, 1),
+		evtChan:   make(chan gocan.Event, 100),
+		closeChan: make(chan struct{}),
+	}
+	for _, id := range cfg.CANFilter {
+		a.filter[id] = struct{}{}
+	}
+	return a
+}
+
+// SetDelay sets the time the ECU takes before answering a frame. The
+// ecu packages often subscribe after sending so it must not be zero
+func (a *Adapter) SetDelay(d time.Duration) {
+	a.delay = d
+}
+
+func (a *Adapter) Name() string {
+	return a.name
+}
+
+func (a *Adapter) Open(ctx context.Context) error {
+	if a.cfg.PrintVersion {
+		a.event(gocan.EventTypeInfo, a.name+" ready")
+	}
+	go a.run(ctx)
+	return nil
+}
+
+func (a *Adapter) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.closeChan)
+		select {
+		case a.errChan <- nil:
+		default:
+		}
+	})
+	return nil
+}
+
+func (a *Adapter) Send() chan<- *gocan.CANFrame {
+	return a.sendChan
+}
+
+func (a *Adapter) Recv() <-chan *gocan.CANFrame {
+	return a.recvChan
+}
+
+func (a *Adapter) Err() <-chan error {
+	return a.errChan
+}
+
+func (a *Adapter) Event() <-chan gocan.Event {
+	return a.evtChan
+}
+
+func (a *Adapter) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.closeChan:
+			return
+		case f := <-a.sendChan:
+			if a.cfg.Debug {
+				a.event(gocan.EventTypeDebug, "<o> "+f.String())
+			}
+			// pad short frames so the simulators can index the full payload
+			if len(f.Data) < 8 {
+				data := make([]byte, 8)
+				copy(data, f.Data)
+				f = gocan.NewFrame(f.Identifier, data, f.FrameType)
+			}
+			a.ecu.Handle(a, f)
+		}
+	}
+}
+
+// Reply sends a frame from the ECU to the tester after the response delay
+func (a *Adapter) Reply(id uint32, data ...byte) {
+	if len(a.filter) > 0 {
+		if _, ok := a.filter[id]; !ok {
+			return
+		}
+	}
+	if a.delay > 0 {
+		time.Sleep(a.delay)
+	}
+	f := gocan.NewFrame(id, data, gocan.Incoming)
+	if a.cfg.Debug {
+		a.event(gocan.EventTypeDebug, "<i> "+f.String())
+	}
+	select {
+	case a.recvChan <- f:
+	case <-a.closeChan:
+	default:
+		a.event(gocan.EventTypeError, "simulator receive channel full")
+	}
+}
+
+func (a *Adapter) event(t gocan.EventType, details string) {
+	select {
+	case a.evtChan <- gocan.Event{Type: t, Details: details}:
+	default:
+	}
+}
+
+// parseConfig reads the simulator settings from AdditionalConfig
+func parseConfig(cfg *gocan.AdapterConfig) (image []byte, delay time.Duration, faults Faults, err error) {
+	delay = -1
+	opts := cfg.AdditionalConfig
+	if opts == nil {
+		return
+	}
+	if filename := opts["image"]; filename != "" {
+		if image, err = os.ReadFile(filename); err != nil {
+			return
+		}
+	}
+	if v := opts["delay"]; v != "" {
+		if delay, err = time.ParseDuration(v); err != nil {
+			return
+		}
+	}
+	if v := opts["drop_ack_every"]; v != "" {
+		if faults.DropAckEvery, err = strconv.Atoi(v); err != nil {
+			return
+		}
+	}
+	if v := opts["bad_keys"]; v != "" {
+		if faults.BadKeys, err = strconv.Atoi(v); err != nil {
+			return
+		}
+	}
+	if v := opts["erase_time"]; v != "" {
+		if faults.EraseTime, err = time.ParseDuration(v); err != nil {
+			return
+		}
+	}
+	if v := opts["reject_loader"]; v != "" {
+		if faults.RejectLoader, err = strconv.ParseBool(v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// register adds a simulator to the gocan adapter list
+func register(name, desc string, newECU func(image []byte, faults Faults, opts map[string]string) (ECU, error)) {
+	if err := gocan.RegisterAdapter(&gocan.AdapterInfo{
+		Name:        name,
+		Description: desc,
+		Capabilities: gocan.AdapterCapabilities{
+			HSCAN: true,
+		},
+		New: func(cfg *gocan.AdapterConfig) (gocan.Adapter, error) {
+			image, delay, faults, err := parseConfig(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			e, err := newECU(image, faults, cfg.AdditionalConfig)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			a := NewAdapter(name, e, cfg)
+			if delay >= 0 {
+				a.SetDelay(delay)
+			}
+			return a, nil
+		},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+var errImageSize = errors.New("invalid image size")
+
+// blank returns an erased flash of the given size
+func blank(size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = 0xFF
+	}
+	return b
+}
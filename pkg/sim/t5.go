@@ -0,0 +1,359 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/roffe/gocan"
+)
+
+func init() {
+	register("Trionic 5 Simulator", "Simulated Trionic 5 ECU with bootloader", func(image []byte, faults Faults, opts map[string]string) (ECU, error) {
+		var chipID byte
+		if v := opts["chip_id"]; v != "" {
+			id, err := strconv.ParseUint(v, 0, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chip_id: %w", err)
+			}
+			chipID = byte(id)
+		}
+		return NewT5(image, chipID, faults)
+	})
+}
+
+const (
+	t5FlashEnd = 0x80000
+	t5SRAMSize = 0x8000
+)
+
+// flash chips known to the t5 packages, device id -> manufacturer id and size
+var t5Chips = map[byte]struct {
+	manufacturer byte
+	size         int
+}{
+	0xB8: {0x89, 0x20000}, // Intel/CSI/OnSemi 28F512
+	0x5D: {0x1F, 0x20000}, // Atmel 29C512
+	0x25: {0x01, 0x20000}, // AMD 28F512
+	0xD5: {0x1F, 0x40000}, // Atmel 29C010
+	0xB5: {0xBF, 0x40000}, // SST 39F010
+	0xB4: {0x89, 0x40000}, // Intel/CSI/OnSemi 28F010
+	0xA7: {0x01, 0x40000}, // AMD 28F010
+	0xA4: {0x37, 0x40000}, // AMIC 29F010
+	0x20: {0x01, 0x40000}, // AMD/ST 29F010
+}
+
+// first bytes of the Legion loader, see t5legion.LegionBootloader
+var legionSignature = []byte{0x22, 0x7C, 0x00, 0xFF, 0xFA, 0x27, 0x12}
+
+// T5 simulates a Trionic 5 on 0x005/0x00C. Until a bootloader has been
+// uploaded and started it only accepts the 0xA5 address and data frames used
+// for the upload, after that it answers the MyBooty commands as well as the
+// Legion ping, read, demand and reset commands
+type T5 struct {
+	mu     sync.Mutex
+	faults Faults
+
+	flash      []byte
+	flashStart int
+	sram       []byte
+	chipID     byte
+
+	running    bool
+	addr, size int
+	acks       int
+
+	// legion read waiting for flow control
+	pending []byte
+}
+
+// NewT5 creates a simulated Trionic 5 with the given flash chip. A zero chipID
+// picks a chip matching the image size, an image smaller than the chip is
+// placed at the end of flash, as a T5.2 bin in a T5.5 ECU. A nil image gives
+// a blank flash with just enough footer and code to be identified as a T5.5
+func NewT5(image []byte, chipID byte, faults Faults) (*T5, error) {
+	if chipID == 0 {
+		switch len(image) {
+		case 0x20000:
+			chipID = 0xB8
+		default:
+			chipID = 0x20
+		}
+	}
+	chip, ok := t5Chips[chipID]
+	if !ok {
+		return nil, fmt.Errorf("unknown flash chip id 0x%02X", chipID)
+	}
+
+	t := &T5{
+		faults:     faults,
+		flash:      blank(chip.size),
+		flashStart: t5FlashEnd - chip.size,
+		sram:       make([]byte, t5SRAMSize),
+		chipID:     chipID,
+	}
+
+	switch {
+	case image == nil:
+		t.writeFooter(0xFD, fmt.Sprintf("%06X", t.flashStart))
+		t.writeFooter(0xFC, "07FFFF")
+		copy(t.flash[0x100:], []byte{0x4E, 0xFA, 0xFB, 0xCC})
+	case len(image) != 0x20000 && len(image) != 0x40000:
+		return nil, fmt.Errorf("%w: %d, Trionic 5 flash is 128 or 256 kB", errImageSize, len(image))
+	case len(image) > chip.size:
+		return nil, fmt.Errorf("%w: %d byte image does not fit a %d byte chip", errImageSize, len(image), chip.size)
+	default:
+		copy(t.flash[chip.size-len(image):], image)
+	}
+	return t, nil
+}
+
+// writeFooter adds an identifier to the footer of a blank flash
+func (t *T5) writeFooter(id byte, value string) {
+	pos := len(t.flash) - 5
+	for t.flash[pos] != 0xFF {
+		pos -= int(t.flash[pos]) + 2
+	}
+	t.flash[pos] = byte(len(value))
+	t.flash[pos-1] = id
+	for i := 0; i < len(value); i++ {
+		t.flash[pos-2-i] = value[i]
+	}
+}
+
+// Image returns a copy of the current flash contents
+func (t *T5) Image() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]byte, len(t.flash))
+	copy(out, t.flash)
+	return out
+}
+
+// Running reports if a bootloader has been uploaded and started
+func (t *T5) Running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+func (t *T5) Handle(a *Adapter, f *gocan.CANFrame) {
+	if f.Identifier != 0x005 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d := f.Data
+	switch {
+	case d[0] == 0xA5:
+		t.addr = int(binary.BigEndian.Uint32(d[1:]))
+		t.size = int(d[5])
+		if !t.running {
+			// only the two low address bytes are usable before the bootloader runs
+			t.addr &= 0xFFFF
+		}
+		a.Reply(0x00C, 0xA5, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	case d[0] == 0xC1:
+		t.running = true
+		// the Legion loader announces itself after the jump and the upload
+		// waits for it, MyBooty stays silent
+		jump := int(binary.BigEndian.Uint32(d[1:]))
+		if jump+len(legionSignature) <= len(t.sram) && bytes.Equal(t.sram[jump:jump+len(legionSignature)], legionSignature) {
+			a.Reply(0x00C, 0x0E, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+		}
+	case d[0]%7 == 0 && d[0] < 0x80:
+		t.data(a, d)
+	case !t.running:
+		// stock firmware ignores everything else
+	case d[0] == 0xC0:
+		t.erase(a)
+	case d[0] == 0xC2:
+		t.running = false
+		a.Reply(0x00C, 0xC2, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00)
+	case d[0] == 0xC7:
+		addr := int(binary.BigEndian.Uint32(d[1:]))
+		out := []byte{0xC7, 0x00, 0, 0, 0, 0, 0, 0}
+		for i := 0; i < 6; i++ {
+			out[2+i] = t.read(addr - i)
+		}
+		a.Reply(0x00C, out...)
+	case d[0] == 0xC8:
+		sum := t.checksum()
+		a.Reply(0x00C, 0xC8, 0x00, byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum), 0x00, 0x00)
+	case d[0] == 0xC9:
+		chip := t5Chips[t.chipID]
+		a.Reply(0x00C, 0xC9, 0x00, 0x00, 0x00, 0x00, 0x00, chip.manufacturer, t.chipID)
+	case d[0] == 0xEF && d[1] == 0xBE:
+		a.Reply(0x00C, 0xDE, 0xAD, 0xF0, 0x0F, 0x00, 0x00, 0x00, 0x00)
+	case d[0] == 0x30:
+		t.flowControl(a)
+	case d[1] == 0x21:
+		t.readDataByLocalIdentifier(a, d)
+	case d[0] == 0x02 && d[1] == 0xA5:
+		t.demand(a, d)
+	case d[0] == 0x01 && d[1] == 0x20:
+		t.running = false
+		a.Reply(0x00C, 0x01, 0x60, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	}
+}
+
+// data handles the 7 byte data frames following an address command, into
+// SRAM before the bootloader runs and into flash after
+func (t *T5) data(a *Adapter, d []byte) {
+	if t.running && t.addr < t.flashStart {
+		// what the t5 packages look for to detect an already running bootloader
+		a.Reply(0x00C, 0x1C, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+		return
+	}
+	for i := 0; i < 7; i++ {
+		offset := int(d[0]) + i
+		if offset >= t.size {
+			break
+		}
+		addr := t.addr + offset
+		switch {
+		case t.running && addr >= t.flashStart && addr < t5FlashEnd:
+			// flash can only clear bits, writing without erasing first corrupts data
+			t.flash[addr-t.flashStart] &= d[1+i]
+		case addr < t5SRAMSize:
+			t.sram[addr] = d[1+i]
+		}
+	}
+	a.Reply(0x00C, d[0], 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+}
+
+func (t *T5) erase(a *Adapter) {
+	if t.faults.EraseTime > 0 {
+		time.Sleep(t.faults.EraseTime)
+	}
+	for i := range t.flash {
+		t.flash[i] = 0xFF
+	}
+	a.Reply(0x00C, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+}
+
+func (t *T5) read(addr int) byte {
+	switch {
+	case addr >= t.flashStart && addr < t5FlashEnd:
+		return t.flash[addr-t.flashStart]
+	case addr >= 0 && addr < t5SRAMSize:
+		return t.sram[addr]
+	}
+	return 0xFF
+}
+
+// romOffset returns the start of the bin according to the footer
+func (t *T5) romOffset() int {
+	pos := len(t.flash) - 5
+	for pos > 1 {
+		length := int(t.flash[pos])
+		if t.flash[pos-1] == 0xFD {
+			var s []byte
+			for i := 0; i < length && pos-2-i >= 0; i++ {
+				s = append(s, t.flash[pos-2-i])
+			}
+			if v, err := strconv.ParseUint(string(s), 16, 32); err == nil && int(v) >= t.flashStart {
+				return int(v)
+			}
+			break
+		}
+		pos -= length + 2
+	}
+	return t.flashStart
+}
+
+// checksum is the byte sum of the bin up to and including the code end marker
+func (t *T5) checksum() uint32 {
+	bin := t.flash[t.romOffset()-t.flashStart:]
+	end := bytes.Index(bin, []byte{0x4E, 0xFA, 0xFB, 0xCC})
+	if end < 0 {
+		return 0
+	}
+	var sum uint32
+	for _, b := range bin[:end+4] {
+		sum += uint32(b)
+	}
+	return sum
+}
+
+// readDataByLocalIdentifier answers the Legion block read, blocks that are
+// all 0xFF are reported as blocks to skip instead of being transferred
+func (t *T5) readDataByLocalIdentifier(a *Adapter, d []byte) {
+	length := int(d[2])
+	addr := int(binary.BigEndian.Uint32(d[3:]))
+	t.pending = make([]byte, length)
+	for i := range t.pending {
+		t.pending[i] = t.read(addr + i)
+	}
+	if length == 0x80 {
+		skip := 0
+		for skip < 0xFF && t.blank(addr+skip*0x80, 0x80) {
+			skip++
+		}
+		if skip > 0 {
+			t.pending = nil
+			a.Reply(0x00C, 0x10, 0x61, byte(length), byte(skip), 0x00, 0x00, 0x00, 0x00)
+			return
+		}
+	}
+	out := []byte{0x10, 0x61, byte(length), 0x00, 0x00, 0x00, 0x00, 0x00}
+	n := copy(out[4:], t.pending)
+	t.pending = t.pending[n:]
+	a.Reply(0x00C, out...)
+}
+
+func (t *T5) blank(addr, length int) bool {
+	if addr+length > t5FlashEnd {
+		return false
+	}
+	for i := 0; i < length; i++ {
+		if t.read(addr+i) != 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+// flowControl sends the rest of a Legion read as consecutive frames
+func (t *T5) flowControl(a *Adapter) {
+	t.acks++
+	if t.faults.dropAck(t.acks) {
+		t.pending = nil
+		return
+	}
+	var seq byte = 0x21
+	for len(t.pending) > 0 {
+		out := make([]byte, 8)
+		out[0] = seq
+		n := copy(out[1:], t.pending)
+		t.pending = t.pending[n:]
+		a.Reply(0x00C, out...)
+		seq++
+		if seq > 0x2F {
+			seq = 0x20
+		}
+	}
+}
+
+// demand answers the Legion IDemand commands
+func (t *T5) demand(a *Adapter, d []byte) {
+	out := []byte{0x02, 0xE5, d[2], 0x01, 0x00, 0x00, 0x00, 0x00}
+	switch d[2] {
+	case 0x00: // set inter frame latency
+	case 0x01: // checksum-32, a 32 bit sum of every flash byte
+		binary.BigEndian.PutUint32(out[4:], checksum32(t.flash))
+	case 0x06: // system information
+		out[4] = byte(t.flashStart >> 16)
+		out[5] = byte(len(t.flash) >> 16)
+		// the device id is reported as 0x1xx, see t5legion.DetermineECU
+		out[6] = 0x01
+		out[7] = t.chipID
+	default:
+		out[3] = 0xFF
+	}
+	a.Reply(0x00C, out...)
+}
@@ -0,0 +1,358 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu/t7sec"
+)
+
+func init() {
+	register("Trionic 7 Simulator", "Simulated Trionic 7 ECU", func(image []byte, faults Faults, opts map[string]string) (ECU, error) {
+		t, err := NewT7(image, faults)
+		if err != nil {
+			return nil, err
+		}
+		if v := opts["key_method"]; v != "" {
+			if t.Method, err = strconv.Atoi(v); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	})
+}
+
+const t7FlashSize = 0x80000
+
+// areas cleared by the erase routine, same as the t7 flash offsets
+var t7EraseAreas = []struct{ start, end int }{
+	{0x000000, 0x07B000},
+	{0x07FF00, 0x080000},
+}
+
+// T7 simulates a Trionic 7 talking KWP2000 over the 0x220/0x238 init and
+// 0x240/0x258/0x266 request, reply and acknowledgement frames
+type T7 struct {
+	mu     sync.Mutex
+	flash  []byte
+	faults Faults
+
+	// Method is the t7sec key method the ECU expects the key to be calculated with
+	Method int
+	// Headers answered to read data by identifier (0x1A)
+	Headers map[byte]string
+	// DTCs answered to read trouble codes by status (0x18), two code bytes
+	// followed by the status byte
+	DTCs [][3]byte
+	// RefuseDTC makes the trouble code read and clear answer conditions not
+	// correct. Clearing keeps codes whose status says the fault is present
+	RefuseDTC bool
+
+	authorized bool
+	seed       int
+	keys       int
+	acks       int
+
+	// multi frame request being received
+	rx     []byte
+	rxLen  int
+	rxLeft int
+
+	// reply frames waiting for an ack from the tester
+	pending [][]byte
+
+	readAddr, readLen int
+	writeAddr         int
+	eraseStart        time.Time
+}
+
+// NewT7 creates a simulated Trionic 7 holding image, a nil image gives a blank flash
+func NewT7(image []byte, faults Faults) (*T7, error) {
+	t := &T7{
+		faults: faults,
+		Headers: map[byte]string{
+			0x90: "YS3EF48E1Y3000001",
+			0x91: "5382089",
+			0x92: "0000000000",
+			0x94: "5382089",
+			0x95: "EF2BX00S.8G",
+			0x97: "B205E EU",
+			0x98: "gocanflasher",
+			0x99: "010101",
+		},
+		// P0105 MAP sensor, P1651 and U2103
+		DTCs: [][3]byte{{0x01, 0x05, 0x60}, {0x16, 0x51, 0x20}, {0xE1, 0x03, 0x60}},
+	}
+	if image == nil {
+		t.flash = blank(t7FlashSize)
+		return t, nil
+	}
+	if len(image) != t7FlashSize {
+		return nil, fmt.Errorf("%w: %d, Trionic 7 flash is %d bytes", errImageSize, len(image), t7FlashSize)
+	}
+	t.flash = make([]byte, t7FlashSize)
+	copy(t.flash, image)
+	return t, nil
+}
+
+// Image returns a copy of the current flash contents
+func (t *T7) Image() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]byte, len(t.flash))
+	copy(out, t.flash)
+	return out
+}
+
+func (t *T7) Handle(a *Adapter, f *gocan.CANFrame) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch f.Identifier {
+	case 0x220:
+		if f.Data[0] == 0x3F && f.Data[1] == 0x81 && f.Data[3] == 0x11 {
+			a.Reply(0x238, 0x40, 0xBF, 0x21, 0xC1, 0x00, 0x11, 0x02, 0x58)
+		}
+	case 0x266:
+		t.acks++
+		if t.faults.dropAck(t.acks) {
+			return
+		}
+		if len(t.pending) > 0 {
+			a.Reply(0x258, t.pending[0]...)
+			t.pending = t.pending[1:]
+		}
+	case 0x240:
+		if f.Data[0]&0x40 == 0x40 {
+			t.pending = nil
+			t.rxLen = int(f.Data[2])
+			t.rxLeft = int(f.Data[0] & 0x3F)
+			t.rx = append(t.rx[:0], f.Data[3:8]...)
+		} else {
+			if t.rx == nil {
+				return
+			}
+			t.rxLeft = int(f.Data[0])
+			t.rx = append(t.rx, f.Data[2:8]...)
+		}
+		if t.rxLeft == 0 {
+			req := t.rx
+			if len(req) > t.rxLen {
+				req = req[:t.rxLen]
+			}
+			t.rx = nil
+			t.request(a, req)
+		}
+	}
+}
+
+// respond splits a KWP reply over as many frames as needed, the first one is
+// sent right away and the rest after each ack from the tester
+func (t *T7) respond(a *Adapter, payload ...byte) {
+	var frames [][]byte
+	first := make([]byte, 8)
+	first[1] = 0xBF
+	first[2] = byte(len(payload))
+	n := copy(first[3:], payload)
+	frames = append(frames, first)
+	for n < len(payload) {
+		f := make([]byte, 8)
+		f[1] = 0xBF
+		n += copy(f[2:], payload[n:])
+		frames = append(frames, f)
+	}
+	for i, f := range frames {
+		f[0] = byte(len(frames) - 1 - i)
+		if i == 0 {
+			f[0] |= 0x40
+		}
+		if i == len(frames)-1 {
+			f[0] |= 0x80
+		}
+	}
+	t.pending = frames[1:]
+	a.Reply(0x258, frames[0]...)
+}
+
+func (t *T7) negative(a *Adapter, service, code byte) {
+	t.respond(a, 0x7F, service, code)
+}
+
+func (t *T7) request(a *Adapter, req []byte) {
+	if len(req) == 0 {
+		return
+	}
+	service := req[0]
+	switch service {
+	case 0x1A: // read data by identifier
+		if len(req) < 2 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		h, ok := t.Headers[req[1]]
+		if !ok {
+			t.negative(a, service, 0x31)
+			return
+		}
+		t.respond(a, append([]byte{0x5A, req[1]}, h...)...)
+	case 0x18: // read trouble codes by status
+		if len(req) < 4 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		if t.RefuseDTC {
+			t.negative(a, service, 0x22)
+			return
+		}
+		out := []byte{0x58, byte(len(t.DTCs))}
+		for _, d := range t.DTCs {
+			out = append(out, d[:]...)
+		}
+		t.respond(a, out...)
+	case 0x14: // clear diagnostic information
+		if len(req) < 3 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		if t.RefuseDTC {
+			t.negative(a, service, 0x22)
+			return
+		}
+		var kept [][3]byte
+		for _, d := range t.DTCs {
+			if d[2]&0x60 == 0x60 {
+				kept = append(kept, d)
+			}
+		}
+		t.DTCs = kept
+		t.respond(a, 0x54, req[1], req[2])
+	case 0x27: // security access
+		t.securityAccess(a, req)
+	case 0x2C: // dynamically define local identifier, used to set the read address
+		if !t.authorized {
+			t.negative(a, service, 0x33)
+			return
+		}
+		if len(req) < 8 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		t.readLen = int(req[4])
+		t.readAddr = int(req[5])<<16 | int(req[6])<<8 | int(req[7])
+		if t.readAddr+t.readLen > len(t.flash) {
+			t.negative(a, service, 0x31)
+			return
+		}
+		t.respond(a, 0x6C, 0xF0)
+	case 0x21: // read data by local identifier
+		if !t.authorized || t.readLen == 0 {
+			t.negative(a, service, 0x33)
+			return
+		}
+		t.respond(a, append([]byte{0x61, 0xF0}, t.flash[t.readAddr:t.readAddr+t.readLen]...)...)
+	case 0x31: // start routine by local identifier
+		if !t.authorized {
+			t.negative(a, service, 0x33)
+			return
+		}
+		if len(req) < 2 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		t.routine(a, req)
+	case 0x3E: // tester present
+		t.respond(a, 0x7E)
+	case 0x34: // request download
+		if !t.authorized {
+			t.negative(a, service, 0x33)
+			return
+		}
+		if len(req) < 4 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		t.writeAddr = int(req[1])<<16 | int(req[2])<<8 | int(req[3])
+		t.respond(a, 0x74)
+	case 0x36: // transfer data
+		if !t.authorized {
+			t.negative(a, service, 0x33)
+			return
+		}
+		data := req[1:]
+		if t.writeAddr+len(data) > len(t.flash) {
+			t.negative(a, service, 0x31)
+			return
+		}
+		// flash can only clear bits, writing without erasing first corrupts data
+		for i, b := range data {
+			t.flash[t.writeAddr+i] &= b
+		}
+		t.writeAddr += len(data)
+		t.respond(a, 0x76)
+	case 0x37: // request transfer exit
+		t.respond(a, 0x77)
+	case 0x82: // stop communication
+		t.authorized = false
+		t.respond(a, 0xC2)
+	case 0x11: // ecu reset
+		t.authorized = false
+		t.readLen = 0
+		t.respond(a, 0x51, 0x81)
+	default:
+		t.negative(a, service, 0x11)
+	}
+}
+
+func (t *T7) securityAccess(a *Adapter, req []byte) {
+	if len(req) < 2 {
+		t.negative(a, 0x27, 0x12)
+		return
+	}
+	switch req[1] {
+	case 0x05:
+		t.seed = rand.IntN(0x10000)
+		t.respond(a, 0x67, 0x05, byte(t.seed>>8), byte(t.seed))
+	case 0x06:
+		if len(req) < 4 {
+			t.negative(a, 0x27, 0x12)
+			return
+		}
+		t.keys++
+		key := int(req[2])<<8 | int(req[3])
+		if t.keys <= t.faults.BadKeys || key != t7sec.CalculateAccessKey(t.seed, t.Method) {
+			t.negative(a, 0x27, 0x35)
+			return
+		}
+		t.authorized = true
+		t.respond(a, 0x67, 0x06, 0x34)
+	default:
+		t.negative(a, 0x27, 0x12)
+	}
+}
+
+func (t *T7) routine(a *Adapter, req []byte) {
+	switch req[1] {
+	case 0x52: // enter EOL mode
+		t.respond(a, 0x71, 0x52)
+	case 0x53: // erase flash
+		if t.eraseStart.IsZero() {
+			t.eraseStart = time.Now()
+		}
+		if time.Since(t.eraseStart) < t.faults.EraseTime {
+			t.negative(a, 0x31, 0x21)
+			return
+		}
+		t.eraseStart = time.Time{}
+		for _, area := range t7EraseAreas {
+			for i := area.start; i < area.end; i++ {
+				t.flash[i] = 0xFF
+			}
+		}
+		t.respond(a, 0x71, 0x53)
+	default:
+		t.negative(a, 0x31, 0x12)
+	}
+}
@@ -0,0 +1,29 @@
+package sim
+
+import (
+	"testing"
+)
+
+func TestT7ShortRequests(t *testing.T) {
+	for _, req := range [][]byte{{0x1A}, {0x2C}, {0x31}, {0x34}, {0x27}, {0x27, 0x06}} {
+		service := req[0]
+		ecu, err := NewT7(nil, Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ecu.authorized = true
+		a := NewAdapter("test", ecu, nil)
+		a.SetDelay(0)
+
+		ecu.request(a, req)
+
+		select {
+		case f := <-a.Recv():
+			if f.Data[3] != 0x7F || f.Data[4] != service || f.Data[5] != 0x12 {
+				t.Errorf("request %X: got %X, want a 0x12 negative response", req, f.Data)
+			}
+		default:
+			t.Errorf("request %X: no response", req)
+		}
+	}
+}
@@ -0,0 +1,677 @@
+package sim
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8sec"
+	"github.com/roffe/gocanflasher/pkg/ecu/t8util"
+)
+
+func init() {
+	register("Trionic 8 Simulator", "Simulated Trionic 8 ECU with MCP", func(image []byte, faults Faults, opts map[string]string) (ECU, error) {
+		var mcp []byte
+		if filename := opts["mcp_image"]; filename != "" {
+			var err error
+			if mcp, err = os.ReadFile(filename); err != nil {
+				return nil, err
+			}
+		}
+		return NewT8(image, mcp, faults)
+	})
+}
+
+const (
+	t8FlashSize  = 0x100000
+	t8MCPSize    = 0x40100
+	t8SRAMStart  = 0x100000
+	t8SRAMSize   = 0x8000
+	t8BlockSize  = 0x80
+	t8DeviceMCP  = 0x05
+	t8DeviceT8   = 0x06
+	t8DeviceMD5  = 0x07
+	t8RequestID  = 0x7E0
+	t8ResponseID = 0x7E8
+	t8UUDTID     = 0x5E8
+)
+
+// T8 simulates a Trionic 8 on 0x7E0/0x7E8. The stock firmware answers the
+// GMLAN services used to enter programming mode and upload a bootloader, once
+// the upload has been started it answers the Legion ping, demand, read,
+// erase, write and exit commands for the main and MCP flash and reads of
+// the SRAM
+type T8 struct {
+	mu     sync.Mutex
+	faults Faults
+
+	flash []byte
+	mcp   []byte
+	sram  []byte
+
+	// Headers answered to read data by identifier (0x1A) and changed by write
+	// data by identifier (0x3B)
+	Headers map[byte]string
+	// ADC values returned by the Legion read ADC pin command
+	ADC map[uint16]byte
+	// DTCs answered to read diagnostic information (0xA9), code, failure
+	// type and status. Clearing (0x04) keeps the ones with the current bit
+	DTCs [][4]byte
+	// Checksum32 is what the Legion CRC-32 command answers, a 32 bit sum of
+	// every flash byte by default
+	Checksum32 func(bin []byte) uint32
+
+	commDisabled bool
+	progRequest  bool
+	programming  bool
+	authorized   bool
+	seed         []byte
+	keys         int
+	downloaded   bool
+
+	running           bool
+	mcpRunning        bool
+	married           bool
+	interFrameLatency uint16
+	md5               []byte
+
+	// segmented request being received
+	rx    []byte
+	rxLen int
+
+	// segmented reply waiting for flow control
+	pending []byte
+	acks    int
+}
+
+// NewT8 creates a simulated Trionic 8, nil images give blank flash
+func NewT8(image, mcp []byte, faults Faults) (*T8, error) {
+	t := &T8{
+		faults: faults,
+		sram:   make([]byte, t8SRAMSize),
+		Headers: map[byte]string{
+			0x90: "YS3FH41U581000001",
+			0x72: "gocanflasher",
+			0x97: "T8",
+			0x92: "SIM",
+			0x02: "\x09\xC4",         // top speed 250 km/h
+			0x29: "\x18\x38",         // rpm limiter 6200
+			0x25: "\x00\x00\x64\x00", // oil quality 100%
+		},
+		// P0106 current and history, P0420 history
+		DTCs: [][4]byte{{0x01, 0x06, 0x00, 0x12}, {0x04, 0x20, 0x00, 0x10}},
+		// 13.5 V battery, 100 kPa and closed throttle and pedal
+		ADC:        map[uint16]byte{0x00: 0xAC, 0x01: 0x54, 0x02: 0x1A, 0x03: 0x1A},
+		Checksum32: checksum32,
+	}
+	if image == nil {
+		t.flash = blank(t8FlashSize)
+	} else {
+		if len(image) != t8FlashSize {
+			return nil, fmt.Errorf("%w: %d, Trionic 8 flash is %d bytes", errImageSize, len(image), t8FlashSize)
+		}
+		t.flash = make([]byte, t8FlashSize)
+		copy(t.flash, image)
+	}
+	if mcp == nil {
+		t.mcp = blank(t8MCPSize)
+	} else {
+		if len(mcp) != t8MCPSize {
+			return nil, fmt.Errorf("%w: %d, Trionic 8 MCP flash is %d bytes", errImageSize, len(mcp), t8MCPSize)
+		}
+		t.mcp = make([]byte, t8MCPSize)
+		copy(t.mcp, mcp)
+	}
+	// something for the firmware to keep in SRAM
+	for i := range t.sram {
+		t.sram[i] = byte(i*5 + i>>8)
+	}
+	return t, nil
+}
+
+// Image returns a copy of the current main flash contents
+func (t *T8) Image() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]byte, len(t.flash))
+	copy(out, t.flash)
+	return out
+}
+
+// SRAM returns a copy of the current SRAM contents
+func (t *T8) SRAM() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]byte, len(t.sram))
+	copy(out, t.sram)
+	return out
+}
+
+// MCPImage returns a copy of the current MCP flash contents
+func (t *T8) MCPImage() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]byte, len(t.mcp))
+	copy(out, t.mcp)
+	return out
+}
+
+// Married returns true once the MCP has been married to the main processor
+func (t *T8) Married() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.married
+}
+
+// Running returns true once the uploaded bootloader has been started
+func (t *T8) Running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+func (t *T8) Handle(a *Adapter, f *gocan.CANFrame) {
+	// tester present on 0x101 never gets an answer
+	if f.Identifier != t8RequestID {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d := f.Data
+	switch {
+	case d[0] == 0x30:
+		t.flowControl(a)
+	case d[0]&0xF0 == 0x20 && t.rx != nil:
+		t.consecutive(a, d)
+	case d[0]&0xF0 == 0x10:
+		t.rxLen = int(d[0]&0x0F)<<8 | int(d[1])
+		t.rx = append([]byte{}, d[2:]...)
+		a.Reply(t8ResponseID, 0x30, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	case t.running:
+		t.legion(a, d)
+	case d[0] >= 1 && d[0] <= 7:
+		t.request(a, d[1:1+d[0]])
+	}
+}
+
+// consecutive collects the rest of a segmented request
+func (t *T8) consecutive(a *Adapter, d []byte) {
+	t.rx = append(t.rx, d[1:]...)
+	if len(t.rx) < t.rxLen {
+		return
+	}
+	req := t.rx[:t.rxLen]
+	t.rx = nil
+	t.request(a, req)
+}
+
+// respond sends a GMLAN reply, segmented if it does not fit a single frame
+func (t *T8) respond(a *Adapter, payload ...byte) {
+	out := make([]byte, 8)
+	if len(payload) <= 7 {
+		out[0] = byte(len(payload))
+		copy(out[1:], payload)
+		a.Reply(t8ResponseID, out...)
+		return
+	}
+	out[0] = 0x10 | byte(len(payload)>>8)
+	out[1] = byte(len(payload))
+	n := copy(out[2:], payload)
+	t.pending = append([]byte{}, payload[n:]...)
+	a.Reply(t8ResponseID, out...)
+}
+
+func (t *T8) negative(a *Adapter, service, code byte) {
+	t.respond(a, 0x7F, service, code)
+}
+
+func (t *T8) request(a *Adapter, req []byte) {
+	if len(req) == 0 {
+		return
+	}
+	service := req[0]
+	switch service {
+	case 0x1A, 0x27, 0xA5:
+		if len(req) < 2 {
+			t.negative(a, service, 0x12)
+			return
+		}
+	}
+	switch service {
+	case 0x04: // clear diagnostic information
+		var kept [][4]byte
+		for _, d := range t.DTCs {
+			if d[3]&0x02 != 0 {
+				kept = append(kept, d)
+			}
+		}
+		t.DTCs = kept
+		t.respond(a, 0x44)
+	case 0x10: // initiate diagnostic operation
+		t.respond(a, 0x50)
+	case 0x1A: // read data by identifier
+		h, ok := t.Headers[req[1]]
+		if !ok {
+			t.negative(a, service, 0x31)
+			return
+		}
+		t.respond(a, append([]byte{0x5A, req[1]}, h...)...)
+	case 0x20: // return to normal mode
+		t.commDisabled, t.progRequest, t.programming = false, false, false
+		t.respond(a, 0x60)
+	case 0x23: // read memory by address
+		t.readMemoryByAddress(a, req)
+	case 0x27: // security access
+		t.securityAccess(a, req)
+	case 0x28: // disable normal communication
+		t.commDisabled = true
+		t.respond(a, 0x68)
+	case 0x34: // request download
+		if !t.programming || !t.authorized {
+			t.negative(a, service, 0x22)
+			return
+		}
+		if len(req) != 6 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		t.respond(a, 0x74)
+	case 0x36: // transfer data
+		t.transferData(a, req)
+	case 0x3B: // write data by identifier
+		if len(req) < 3 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		if !t.authorized {
+			t.negative(a, service, 0x33)
+			return
+		}
+		t.Headers[req[1]] = string(req[2:])
+		t.respond(a, 0x7B, req[1])
+	case 0x3E: // tester present
+		t.respond(a, 0x7E)
+	case 0xA9: // read diagnostic information, status of DTC by status mask
+		if len(req) < 3 || req[1] != 0x81 {
+			t.negative(a, service, 0x12)
+			return
+		}
+		t.negative(a, service, 0x78)
+		var frames [][]byte
+		for _, d := range t.DTCs {
+			if d[3]&req[2] != 0 {
+				frames = append(frames, []byte{0x81, d[0], d[1], d[2], d[3], 0x00, 0x00, 0x00})
+			}
+		}
+		frames = append(frames, []byte{0x81, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+		// the codes follow as UUDT frames
+		for _, f := range frames {
+			a.Reply(t8UUDTID, f...)
+		}
+	case 0xA2: // report programmed state
+		t.respond(a, 0xE2, 0x00)
+	case 0xA5: // programming mode
+		switch req[1] {
+		case 0x01, 0x02:
+			if !t.commDisabled {
+				t.negative(a, service, 0x22)
+				return
+			}
+			t.progRequest = true
+			t.respond(a, 0xE5)
+		case 0x03:
+			// enable programming mode has no response
+			t.programming = t.progRequest
+		default:
+			t.negative(a, service, 0x12)
+		}
+	default:
+		t.negative(a, service, 0x11)
+	}
+}
+
+// readMemoryByAddress answers stock firmware memory reads of the flash and
+// SRAM, they need security access and the boot partition is never handed out
+func (t *T8) readMemoryByAddress(a *Adapter, req []byte) {
+	if len(req) < 6 {
+		t.negative(a, 0x23, 0x12)
+		return
+	}
+	if !t.authorized {
+		t.negative(a, 0x23, 0x33)
+		return
+	}
+	addr := int(req[1])<<16 | int(req[2])<<8 | int(req[3])
+	length := int(req[4])<<8 | int(req[5])
+	if addr >= t8SRAMStart && length > 0 && length <= 0xFB && addr+length <= t8SRAMStart+t8SRAMSize {
+		addr -= t8SRAMStart
+		t.respond(a, append([]byte{0x63, req[1], req[2], req[3]}, t.sram[addr:addr+length]...)...)
+		return
+	}
+	_, bootEnd := t8util.PartitionRange(1)
+	if length == 0 || length > 0xFB || addr < int(bootEnd) || addr+length > len(t.flash) {
+		t.negative(a, 0x23, 0x31)
+		return
+	}
+	t.respond(a, append([]byte{0x63, req[1], req[2], req[3]}, t.flash[addr:addr+length]...)...)
+}
+
+func (t *T8) securityAccess(a *Adapter, req []byte) {
+	switch req[1] {
+	case 0x01:
+		if t.authorized {
+			t.respond(a, 0x67, 0x01, 0x00, 0x00)
+			return
+		}
+		// a zero seed means access is already granted
+		t.seed = []byte{byte(rand.IntN(0x100)), byte(1 + rand.IntN(0xFF))}
+		t.respond(a, 0x67, 0x01, t.seed[0], t.seed[1])
+	case 0x02:
+		t.keys++
+		if t.seed == nil || len(req) < 4 {
+			t.negative(a, 0x27, 0x22)
+			return
+		}
+		high, low := t8sec.CalculateAccessKey(t.seed, 0x01)
+		if t.keys <= t.faults.BadKeys || req[2] != high || req[3] != low {
+			t.negative(a, 0x27, 0x35)
+			return
+		}
+		t.authorized = true
+		t.respond(a, 0x67, 0x02)
+	default:
+		t.negative(a, 0x27, 0x12)
+	}
+}
+
+// transferData stores a bootloader block in SRAM or, with sub function 0x80,
+// starts the code at the given address. Blocks below SRAM are programmed
+// into the main flash, by Legion when it runs and by the factory bootloader
+// otherwise. With Legion running sub function 0x05 programs the MCP flash
+func (t *T8) transferData(a *Adapter, req []byte) {
+	if !t.programming || !t.authorized {
+		t.negative(a, 0x36, 0x22)
+		return
+	}
+	if len(req) < 6 {
+		t.negative(a, 0x36, 0x12)
+		return
+	}
+	addr := int(binary.BigEndian.Uint32(req[2:])) - t8SRAMStart
+	data := req[6:]
+	if t.running && req[1] == 0x00 && addr < 0 {
+		t.program(a, t.flash, addr+t8SRAMStart, data)
+		return
+	}
+	if t.running && req[1] == t8DeviceMCP && t.mcpRunning {
+		t.program(a, t.mcp, addr+t8SRAMStart, data)
+		return
+	}
+	if !t.running && req[1] == 0x00 && addr < 0 {
+		// erasing by the factory bootloader is not simulated, the data is
+		// copied over whatever was there
+		if addr+t8SRAMStart+len(data) > len(t.flash) {
+			t.negative(a, 0x36, 0x31)
+			return
+		}
+		copy(t.flash[addr+t8SRAMStart:], data)
+		t.respond(a, 0x76)
+		return
+	}
+	if t.faults.RejectLoader {
+		t.negative(a, 0x36, 0x31)
+		return
+	}
+	if addr < 0 || addr+len(data) > len(t.sram) {
+		t.negative(a, 0x36, 0x31)
+		return
+	}
+	switch req[1] {
+	case 0x00:
+		copy(t.sram[addr:], data)
+		t.downloaded = true
+		t.respond(a, 0x76)
+	case 0x80:
+		if !t.downloaded {
+			t.negative(a, 0x36, 0x22)
+			return
+		}
+		t.running = true
+		t.respond(a, 0x76)
+	default:
+		t.negative(a, 0x36, 0x12)
+	}
+}
+
+// legion answers the commands of the uploaded Legion bootloader
+func (t *T8) legion(a *Adapter, d []byte) {
+	switch {
+	case d[0] == 0xEF && d[1] == 0xBE:
+		a.Reply(t8ResponseID, 0xDE, 0xAD, 0xF0, 0x0F, 0x00, 0x00, 0x00, 0x00)
+	case d[1] == 0x21:
+		t.readDataByLocalIdentifier(a, d)
+	case d[0] == 0x02 && d[1] == 0xA5:
+		t.demand(a, d)
+	case d[0] == 0x05 && d[1] == 0x31:
+		t.erase(a, d)
+	case d[0] == 0x01 && d[1] == 0x20:
+		t.reset()
+		a.Reply(t8ResponseID, 0x01, 0x60, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+	}
+}
+
+// erase answers the Legion erase command, bit n of the mask erases main or
+// MCP flash partition n+1
+func (t *T8) erase(a *Adapter, d []byte) {
+	mem, partition := t.flash, t8util.PartitionRange
+	switch {
+	case d[2] == t8DeviceT8:
+	case d[2] == t8DeviceMCP && t.mcpRunning:
+		mem, partition = t.mcp, t8util.MCPPartitionRange
+	default:
+		a.Reply(t8ResponseID, 0x03, 0x7F, 0x31, 0x31, 0x00, 0x00, 0x00, 0x00)
+		return
+	}
+	a.Reply(t8ResponseID, 0x03, 0x7F, 0x31, 0x78, 0x00, 0x00, 0x00, 0x00)
+	if t.faults.EraseTime > 0 {
+		time.Sleep(t.faults.EraseTime)
+	}
+	mask := binary.BigEndian.Uint16(d[4:])
+	for p := 1; p <= 9; p++ {
+		if mask&(1<<(p-1)) == 0 {
+			continue
+		}
+		start, end := partition(p)
+		for i := start; i < end; i++ {
+			mem[i] = 0xFF
+		}
+	}
+	a.Reply(t8ResponseID, 0x02, 0x71, d[2], 0x00, 0x00, 0x00, 0x00, 0x00)
+}
+
+// program writes a transfer data block to mem, like real flash only bits
+// that are still set can change
+func (t *T8) program(a *Adapter, mem []byte, addr int, data []byte) {
+	if addr < 0 || addr+len(data) > len(mem) {
+		t.negative(a, 0x36, 0x31)
+		return
+	}
+	for i, b := range data {
+		mem[addr+i] &= b
+	}
+	t.respond(a, 0x76)
+}
+
+// reset restarts the stock firmware, the bootloader in SRAM is lost
+func (t *T8) reset() {
+	t.running, t.mcpRunning, t.downloaded = false, false, false
+	t.commDisabled, t.progRequest, t.programming, t.authorized = false, false, false, false
+	t.seed, t.md5 = nil, nil
+	for i := range t.sram {
+		t.sram[i] = 0
+	}
+}
+
+// device returns the memory read by the Legion for the given device byte
+func (t *T8) device(dev byte) []byte {
+	switch dev {
+	case t8DeviceT8:
+		return t.flash
+	case t8DeviceMCP:
+		if t.mcpRunning {
+			return t.mcp
+		}
+	case t8DeviceMD5:
+		return t.md5
+	}
+	return nil
+}
+
+// readDataByLocalIdentifier answers the Legion block read, blocks that are
+// all 0xFF are reported as blocks to skip instead of being transferred
+func (t *T8) readDataByLocalIdentifier(a *Adapter, d []byte) {
+	mem := t.device(d[0])
+	length := int(d[2])
+	addr := int(binary.BigEndian.Uint32(d[3:]))
+	if d[0] == t8DeviceT8 && addr >= t8SRAMStart {
+		mem, addr = t.sram, addr-t8SRAMStart
+	}
+	if mem == nil || addr+length > len(mem) {
+		a.Reply(t8ResponseID, 0x01, 0x7E, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+		return
+	}
+	if length == t8BlockSize {
+		skip := 0
+		for skip < 0xFF && t8Blank(mem, addr+skip*t8BlockSize, t8BlockSize) {
+			skip++
+		}
+		if skip > 0 {
+			t.pending = nil
+			a.Reply(t8ResponseID, 0x10, 0x61, byte(length), byte(skip), 0x00, 0x00, 0x00, 0x00)
+			return
+		}
+	}
+	out := []byte{0x10, 0x61, byte(length), 0x00, 0x00, 0x00, 0x00, 0x00}
+	n := copy(out[4:], mem[addr:addr+length])
+	t.pending = append([]byte{}, mem[addr+n:addr+length]...)
+	a.Reply(t8ResponseID, out...)
+}
+
+func t8Blank(mem []byte, addr, length int) bool {
+	if addr+length > len(mem) {
+		return false
+	}
+	for _, b := range mem[addr : addr+length] {
+		if b != 0xFF {
+			return false
+		}
+	}
+	return true
+}
+
+// flowControl sends the rest of a segmented reply as consecutive frames
+func (t *T8) flowControl(a *Adapter) {
+	t.acks++
+	if t.faults.dropAck(t.acks) {
+		t.pending = nil
+		return
+	}
+	var seq byte = 0x21
+	for len(t.pending) > 0 {
+		out := make([]byte, 8)
+		out[0] = seq
+		n := copy(out[1:], t.pending)
+		t.pending = t.pending[n:]
+		a.Reply(t8ResponseID, out...)
+		seq++
+		if seq > 0x2F {
+			seq = 0x20
+		}
+	}
+}
+
+// demand answers the Legion IDemand commands
+func (t *T8) demand(a *Adapter, d []byte) {
+	out := []byte{0x02, 0xE5, d[2], 0x01, 0x00, 0x00, 0x00, 0x00}
+	wish := binary.BigEndian.Uint16(d[6:])
+	switch d[2] {
+	case 0x00: // set inter frame latency
+		t.interFrameLatency = wish
+	case 0x01: // checksum-32
+		bin := t.flash
+		if wish == 1 {
+			bin = t.mcp
+		}
+		binary.BigEndian.PutUint32(out[4:], t.Checksum32(bin))
+	case 0x02: // trionic 8 md5, read back from device 7
+		sum, ok := t8MD5(t.flash, int(wish))
+		if !ok {
+			out[3] = 0xFF
+			break
+		}
+		t.md5 = sum
+	case 0x03: // trionic 8 mcp md5
+		if !t.mcpRunning || wish > 9 {
+			out[3] = 0xFF
+			break
+		}
+		t.md5 = t8MCPMD5(t.mcp, int(wish))
+	case 0x04: // start secondary bootloader
+		t.mcpRunning = true
+	case 0x05: // marry secondary processor
+		if !t.mcpRunning {
+			out[3] = 0xFF
+			break
+		}
+		t.married = true
+	case 0x06: // read adc pin
+		out[4] = t.ADC[wish]
+	default:
+		out[3] = 0xFF
+	}
+	a.Reply(t8ResponseID, out...)
+}
+
+// t8MD5 sums a partition the same way as the Legion, see t8util.GetPartitionMD5
+func t8MD5(bin []byte, partition int) ([]byte, bool) {
+	switch {
+	case partition < 10:
+		return t8util.GetPartitionMD5(bin, 6, partition), true
+	case partition < 13:
+		if int(t8util.GetLasAddress(bin)) > len(bin) {
+			return nil, false
+		}
+		return t8util.GetPartitionMD5(bin, 6, partition), true
+	}
+	return nil, false
+}
+
+// t8MCPMD5 sums the whole MCP flash, one of its 32k partitions or the
+// shadow area as partition 9
+func t8MCPMD5(bin []byte, partition int) []byte {
+	start, end := 0, len(bin)
+	switch {
+	case partition == 9:
+		start, end = 0x40000, 0x40100
+	case partition > 0:
+		end = partition << 15
+		start = end - 0x8000
+	}
+	sum := md5.Sum(bin[start:end])
+	return sum[:]
+}
+
+// checksum32 adds up every byte of bin like Legion does
+func checksum32(bin []byte) uint32 {
+	var sum uint32
+	for _, b := range bin {
+		sum += uint32(b)
+	}
+	return sum
+}
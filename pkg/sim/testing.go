@@ -0,0 +1,37 @@
+package sim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// Connect returns a client talking to e through an adapter without response
+// delay, the client is closed when the test ends
+func Connect(tb testing.TB, e ECU) *gocan.Client {
+	tb.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+	a := NewAdapter("test", e, nil)
+	a.SetDelay(0)
+	c, err := gocan.NewWithOpts(ctx, a)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { c.Close() })
+	return c
+}
+
+// Quiet silences the progress, message and error callbacks of cfg, a nil cfg
+// gives a new config
+func Quiet(cfg *ecu.Config) *ecu.Config {
+	if cfg == nil {
+		cfg = &ecu.Config{}
+	}
+	cfg.OnProgress = func(float64) {}
+	cfg.OnMessage = func(string) {}
+	cfg.OnError = func(error) {}
+	return cfg
+}
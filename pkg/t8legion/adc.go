@@ -0,0 +1,90 @@
+package t8legion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// ADCChannel is an analog input read with the ReadADCPin command. The loader
+// returns the top 8 bits of the conversion, 0-255 over 0-5 V at the pin
+type ADCChannel struct {
+	Name  string
+	Pin   uint16
+	Unit  string
+	Scale func(volt float64) float64
+}
+
+// ADCChannels are the Trionic 8 inputs useful on the bench. The pin numbers
+// and scalings have not been checked against a schematic or a running ECU,
+// treat the values as a hint
+var ADCChannels = []ADCChannel{
+	// battery is fed through a 1:4 divider
+	{Name: "battery", Pin: 0, Unit: "V", Scale: func(v float64) float64 { return v * 4 }},
+	// 3 bar sensor, 0.5 V at 20 kPa up to 4.5 V at 300 kPa
+	{Name: "map", Pin: 1, Unit: "kPa", Scale: func(v float64) float64 { return 20 + (v-0.5)*70 }},
+	{Name: "throttle", Pin: 2, Unit: "%", Scale: func(v float64) float64 { return (v - 0.5) / 4 * 100 }},
+	{Name: "pedal", Pin: 3, Unit: "%", Scale: func(v float64) float64 { return (v - 0.5) / 4 * 100 }},
+}
+
+// MinFlashVoltage is the battery voltage CheckBattery warns below
+const MinFlashVoltage = 11.5
+
+// ReadADC returns the raw value of an ADC pin
+func (t *Client) ReadADC(ctx context.Context, pin uint16) (byte, error) {
+	if !t.legionRunning {
+		return 0, ecu.ErrBootloaderNotRunning
+	}
+	b, err := t.IDemand(ctx, ReadADCPin, pin)
+	if err != nil {
+		return 0, fmt.Errorf("read adc pin %d: %w", pin, err)
+	}
+	return b[0], nil
+}
+
+// ReadChannel reads and scales one channel
+func (t *Client) ReadChannel(ctx context.Context, ch ADCChannel) (ecu.Reading, error) {
+	raw, err := t.ReadADC(ctx, ch.Pin)
+	if err != nil {
+		return ecu.Reading{}, err
+	}
+	return ecu.Reading{
+		Name:  ch.Name,
+		Unit:  ch.Unit,
+		Value: ch.Scale(adcVolt(raw)),
+		Raw:   int(raw),
+	}, nil
+}
+
+// adcVolt converts a raw value to the voltage at the pin
+func adcVolt(raw byte) float64 {
+	return float64(raw) * 5 / 255
+}
+
+// LookupADCChannel returns the named channel from ADCChannels
+func LookupADCChannel(name string) (ADCChannel, bool) {
+	for _, ch := range ADCChannels {
+		if ch.Name == name {
+			return ch, true
+		}
+	}
+	return ADCChannel{}, false
+}
+
+// CheckBattery reports the battery voltage and warns through OnError if it
+// reads below MinFlashVoltage, a sagging supply halfway through a write can
+// brick the ECU. The battery channel is unverified so the flash is never
+// stopped on this reading
+func (t *Client) CheckBattery(ctx context.Context) {
+	ch, _ := LookupADCChannel("battery")
+	r, err := t.ReadChannel(ctx, ch)
+	if err != nil {
+		t.cfg.OnError(fmt.Errorf("battery check skipped: %w", err))
+		return
+	}
+	t.cfg.OnMessage(fmt.Sprintf("Battery voltage %.1f V", r.Value))
+	if r.Value < MinFlashVoltage {
+		t.cfg.OnError(fmt.Errorf("battery voltage reads %.1f V, below %.1f V, make sure a charger is connected", r.Value, MinFlashVoltage))
+	}
+}
@@ -0,0 +1,74 @@
+package t8legion
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+	"github.com/roffe/gocanflasher/pkg/sim"
+)
+
+func TestADCScaling(t *testing.T) {
+	tests := []struct {
+		channel string
+		raw     byte
+		want    float64
+	}{
+		{"battery", 0, 0},
+		{"battery", 153, 12},
+		{"battery", 255, 20},
+		{"map", 26, 20.7},
+		{"map", 230, 300.7},
+		{"throttle", 26, 0.25},
+		{"throttle", 230, 100.2},
+		{"pedal", 128, 50.2},
+	}
+	for _, tt := range tests {
+		ch, ok := LookupADCChannel(tt.channel)
+		if !ok {
+			t.Fatalf("no %s channel", tt.channel)
+		}
+		if got := ch.Scale(adcVolt(tt.raw)); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s raw %d: got %.2f %s, want %.2f", tt.channel, tt.raw, got, ch.Unit, tt.want)
+		}
+	}
+}
+
+func TestCheckBattery(t *testing.T) {
+	tests := []struct {
+		raw  byte
+		warn bool
+	}{
+		{raw: 0xAC},
+		{raw: 0x80, warn: true},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		e, err := sim.NewT8(nil, nil, sim.Faults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.ADC[0] = tt.raw
+		var warnings []error
+		cfg := sim.Quiet(&ecu.Config{
+			// the reading is only a hint, nothing may be asked
+			OnConfirm: func(q string) bool {
+				t.Errorf("raw 0x%02X: asked %q", tt.raw, q)
+				return false
+			},
+		})
+		cfg.OnError = func(err error) { warnings = append(warnings, err) }
+		l := New(sim.Connect(t, e), cfg, 0x7E0, 0x7E8)
+		if err := l.Bootstrap(ctx); err != nil {
+			t.Fatal(err)
+		}
+		warnings = nil
+		l.CheckBattery(ctx)
+		if (len(warnings) > 0) != tt.warn {
+			t.Errorf("raw 0x%02X: got warnings %v, want %v", tt.raw, warnings, tt.warn)
+		}
+	}
+}
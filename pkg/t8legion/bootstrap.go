@@ -3,11 +3,13 @@ package t8legion
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/roffe/gocan/pkg/gmlan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
 	"github.com/roffe/gocanflasher/pkg/ecu/t8sec"
 )
 
@@ -43,12 +45,12 @@ func (t *Client) Alive(ctx context.Context) bool {
 
 func (t *Client) Bootstrap(ctx context.Context) error {
 	if !t.Alive(ctx) {
-		if err := t.bootstrapPreFlight(ctx); err != nil {
+		if err := t.EnterProgrammingMode(ctx); err != nil {
 			return err
 		}
 		time.Sleep(100 * time.Millisecond)
 		if err := t.UploadBootloader(ctx); err != nil {
-			return err
+			return fmt.Errorf("%w: %w", ErrBootloaderUpload, err)
 		}
 		t.cfg.OnMessage("starting bootloader")
 		if err := t.StartBootloader(ctx, 0x102400); err != nil {
@@ -63,44 +65,56 @@ func (t *Client) Bootstrap(ctx context.Context) error {
 			return err
 		}
 	} else {
-		return errors.New("legion is not running")
+		return ecu.ErrBootloaderNotRunning
 	}
 
 	return nil
 }
 
-func (t *Client) bootstrapPreFlight(ctx context.Context) error {
+// ErrBootloaderUpload is returned by Bootstrap when the factory bootloader
+// did not accept Legion, the ECU is left in programming mode
+var ErrBootloaderUpload = errors.New("bootloader upload failed")
+
+// EnterProgrammingMode puts the factory bootloader in programming mode and
+// requests security access, as needed to upload Legion or to download to the
+// flash with StockDownload
+func (t *Client) EnterProgrammingMode(ctx context.Context) error {
 	t.gm.TesterPresentNoResponseAllowed()
 
 	//time.Sleep(50 * time.Millisecond)
 
-	if err := t.gm.InitiateDiagnosticOperation(ctx, 0x02); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.InitiateDiagnosticOperation(ctx, 0x02) }); err != nil {
 		return err
 	}
 
-	if err := t.gm.DisableNormalCommunication(ctx); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.DisableNormalCommunication(ctx) }); err != nil {
 		return err
 	}
 
-	if b, err := t.gm.ReportProgrammedState(ctx); err != nil {
+	var state byte
+	if err := t.gmCall(ctx, func() (err error) {
+		state, err = t.gm.ReportProgrammedState(ctx)
+		return err
+	}); err != nil {
 		return err
-	} else {
-		t.cfg.OnMessage("ECU Programmed state: " + gmlan.TranslateProgrammedState(b))
 	}
+	t.cfg.OnMessage("ECU Programmed state: " + gmlan.TranslateProgrammedState(state))
 
-	if err := t.gm.ProgrammingModeRequest(ctx); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.ProgrammingModeRequest(ctx) }); err != nil {
 		return err
 	}
 
-	if err := t.gm.ProgrammingModeEnable(ctx); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.ProgrammingModeEnable(ctx) }); err != nil {
 		return err
 	}
 
 	time.Sleep(50 * time.Millisecond)
 
 	t.gm.TesterPresentNoResponseAllowed()
-	if err := t.gm.RequestSecurityAccess(ctx, 0x01, 0, t8sec.CalculateAccessKey); err != nil {
-		return err
+	if err := t.gmCall(ctx, func() error {
+		return t.gm.RequestSecurityAccess(ctx, 0x01, 0, t8sec.CalculateAccessKey)
+	}); err != nil {
+		return ecu.SecurityAccess(err)
 	}
 
 	return nil
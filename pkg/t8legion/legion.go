@@ -75,12 +75,17 @@ func (t *Client) IsRunning() bool {
 	return t.legionRunning
 }
 
+// gmCall runs a gmlan request, see ecu.GMLAN
+func (t *Client) gmCall(ctx context.Context, fn func() error) error {
+	return ecu.GMLAN(ctx, t.c, t.recvID, fn)
+}
+
 func (t *Client) StartBootloader(ctx context.Context, startAddress uint32) error {
-	return t.gm.Execute(ctx, startAddress)
+	return t.gmCall(ctx, func() error { return t.gm.Execute(ctx, startAddress) })
 }
 
 func (t *Client) UploadBootloader(ctx context.Context) error {
-	if err := t.gm.RequestDownload(ctx, false); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.RequestDownload(ctx, false) }); err != nil {
 		return err
 	}
 	startAddress := 0x102400
@@ -89,8 +94,7 @@ func (t *Client) UploadBootloader(ctx context.Context) error {
 
 	start := time.Now()
 
-	t.cfg.OnProgress(-float64(9997))
-	t.cfg.OnProgress(float64(0))
+	phase := t.cfg.StartPhase(ecu.PhaseBootloader, len(bootloaderBytes))
 	t.cfg.OnMessage("Uploading bootloader " + strconv.Itoa(len(bootloaderBytes)) + " bytes")
 
 	r := bytes.NewReader(bootloaderBytes)
@@ -103,7 +107,7 @@ func (t *Client) UploadBootloader(ctx context.Context) error {
 			t.gm.TesterPresentNoResponseAllowed()
 			pp = 0
 		}
-		if err := t.gm.TransferData(ctx, 0x00, 0xF0, startAddress); err != nil {
+		if err := t.gmCall(ctx, func() error { return t.gm.TransferData(ctx, 0x00, 0xF0, startAddress) }); err != nil {
 			return err
 		}
 		seq = 0x21
@@ -126,13 +130,13 @@ func (t *Client) UploadBootloader(ctx context.Context) error {
 			if seq > 0x2F {
 				seq = 0x20
 			}
-			t.cfg.OnProgress(float64(progress))
+			phase.Update(progress)
 		}
 		resp, err := t.c.Recv(ctx, t.defaultTimeout*2, t.recvID...)
 		if err != nil {
 			return err
 		}
-		if err := gmlan.CheckErr(resp); err != nil {
+		if err := ecu.CheckGMLAN(resp); err != nil {
 			log.Println(resp.String())
 			return err
 		}
@@ -142,7 +146,7 @@ func (t *Client) UploadBootloader(ctx context.Context) error {
 		startAddress += 0xEA
 	}
 
-	if err := t.gm.TransferData(ctx, 0x00, 0x0A, startAddress); err != nil {
+	if err := t.gmCall(ctx, func() error { return t.gm.TransferData(ctx, 0x00, 0x0A, startAddress) }); err != nil {
 		return err
 	}
 
@@ -157,7 +161,7 @@ func (t *Client) UploadBootloader(ctx context.Context) error {
 		return err
 	}
 
-	if err := gmlan.CheckErr(resp); err != nil {
+	if err := ecu.CheckGMLAN(resp); err != nil {
 		return err
 	}
 
@@ -168,7 +172,7 @@ func (t *Client) UploadBootloader(ctx context.Context) error {
 
 	startAddress += 0x06
 
-	t.cfg.OnProgress(float64(9997))
+	phase.Done()
 	t.cfg.OnMessage(fmt.Sprintf("Done, took: %s", time.Since(start).String()))
 
 	return nil
@@ -178,15 +182,15 @@ func (t *Client) Ping(ctx context.Context) error {
 	frame := gocan.NewFrame(t.canID, []byte{0xEF, 0xBE, 0x00, 0x00, 0x00, 0x00, 0x33, 0x66}, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, 50*time.Millisecond, t.recvID...)
 	if err != nil {
-		return errors.New("LegionPing: " + err.Error())
+		return fmt.Errorf("LegionPing: %w", ecu.NoResponse(err))
 	}
 	if resp.Data[0] == 0xDE && resp.Data[1] == 0xAD { //&& d[2] == 0xF0 && d[3] == 0x0F {
 		return nil
 	}
-	if err := gmlan.CheckErr(resp); err != nil {
-		return errors.New("LegionPing: " + err.Error())
+	if err := ecu.CheckGMLAN(resp); err != nil {
+		return fmt.Errorf("LegionPing: %w", err)
 	}
-	return errors.New("LegionPing: no response")
+	return fmt.Errorf("LegionPing: %w", ecu.ErrNoResponse)
 }
 
 func (t *Client) Exit(ctx context.Context) error {
@@ -196,15 +200,16 @@ func (t *Client) Exit(ctx context.Context) error {
 	frame := gocan.NewFrame(t.canID, payload, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout*2, t.recvID...)
 	if err != nil {
-		return errors.New("LegionExit: " + err.Error())
+		return fmt.Errorf("LegionExit: %w", ecu.NoResponse(err))
 	}
 
 	if resp.Data[0] == 0x01 && resp.Data[1] == 0x60 {
+		t.legionRunning = false
 		return nil
 	}
 
-	if err := gmlan.CheckErr(resp); err != nil {
-		return errors.New("LegionExit: " + err.Error())
+	if err := ecu.CheckGMLAN(resp); err != nil {
+		return fmt.Errorf("LegionExit: %w", err)
 	}
 
 	return fmt.Errorf("LegionExit: invalid response %X", resp.Data)
@@ -228,7 +233,11 @@ func (t *Client) GetMD5(ctx context.Context, md5type Command, partition uint16)
 		}
 		return md5sum, nil
 	case GetTrionic8MCPMD5:
-		return nil, nil
+		md5sum, err := t.IDemand(ctx, GetTrionic8MCPMD5, partition)
+		if err != nil {
+			return nil, fmt.Errorf("legion: failed to get mcp md5 for block %d: %w", partition, err)
+		}
+		return md5sum, nil
 	default:
 		return nil, errors.New("invalid md5 type")
 	}
@@ -291,7 +300,7 @@ func (t *Client) IDemand(ctx context.Context, command Command, wish uint16) ([]b
 	err := retry.Do(func() error {
 		resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout, t.recvID...)
 		if err != nil {
-			return fmt.Errorf("IDemand: %w", err)
+			return fmt.Errorf("IDemand: %w", ecu.NoResponse(err))
 		}
 
 		if err := demandErr(command, resp.Data); err != nil {
@@ -347,34 +356,57 @@ func (t *Client) IDemand(ctx context.Context, command Command, wish uint16) ([]b
 	return out, nil
 }
 
+// ReadFlash reads lastAddress bytes from the start of device. z22se reads
+// the way the Z22SE loader expects, every block is transferred instead of
+// blank ones being reported
 func (t *Client) ReadFlash(ctx context.Context, device byte, lastAddress int, z22se bool) ([]byte, error) {
 	if !t.legionRunning {
-		return nil, fmt.Errorf("legion not running")
+		return nil, ecu.ErrBootloaderNotRunning
+	}
+	t.cfg.OnMessage("Downloading " + strconv.Itoa(lastAddress) + " bytes")
+	phase := t.cfg.StartPhase(ecu.PhaseRead, lastAddress)
+	buf, err := t.readMemory(ctx, device, 0, lastAddress, z22se, phase)
+	if err != nil {
+		return nil, err
+	}
+	phase.Done()
+	return buf, nil
+}
+
+// ReadMemory reads length bytes starting at address from device. Blocks the
+// loader reports as unprogrammed are returned as 0xFF. For EcuByte_T8 the
+// whole address space can be read, SRAM included. phase is advanced by the
+// number of bytes read if not nil
+func (t *Client) ReadMemory(ctx context.Context, device byte, address, length int, phase *ecu.ProgressReporter) ([]byte, error) {
+	return t.readMemory(ctx, device, address, length, false, phase)
+}
+
+func (t *Client) readMemory(ctx context.Context, device byte, address, length int, z22se bool, phase *ecu.ProgressReporter) ([]byte, error) {
+	if !t.legionRunning {
+		return nil, ecu.ErrBootloaderNotRunning
 	}
-	buf := make([]byte, lastAddress)
+	buf := make([]byte, length)
 	bufpnt := 0
 
 	// Pre-fill buffer with 0xFF (unprogrammed FLASH chip value)
-	buf[0] = 0xFF
-	for j := 1; j < len(buf); j *= 2 {
-		copy(buf[j:], buf[:j])
+	if length > 0 {
+		buf[0] = 0xFF
+		for j := 1; j < len(buf); j *= 2 {
+			copy(buf[j:], buf[:j])
+		}
 	}
 
-	t.cfg.OnMessage("Downloading " + strconv.Itoa(lastAddress) + " bytes")
-	t.cfg.OnProgress(-float64(lastAddress))
-	t.cfg.OnProgress(float64(0))
-
-	var blockSize byte = 0x80
-
-	for bufpnt < lastAddress && ctx.Err() == nil {
+	for bufpnt < length && ctx.Err() == nil {
+		blockSize := byte(min(0x80, length-bufpnt))
+		before := bufpnt
 		err := retry.Do(
 			func() error {
-				b, blocksToSkip, err := t.ReadDataByLocalIdentifier(ctx, true, device, bufpnt, blockSize)
+				b, blocksToSkip, err := t.ReadDataByLocalIdentifier(ctx, !z22se, device, address+bufpnt, blockSize)
 				if err != nil {
 					return err
 				}
 				if blocksToSkip > 0 {
-					bufpnt += (blocksToSkip * int(blockSize))
+					bufpnt = min(bufpnt+blocksToSkip*int(blockSize), length)
 				} else if len(b) == int(blockSize) {
 					copy(buf[bufpnt:], b)
 					bufpnt += int(blockSize)
@@ -386,7 +418,7 @@ func (t *Client) ReadFlash(ctx context.Context, device byte, lastAddress int, z2
 			retry.Attempts(10),
 			retry.Context(ctx),
 			retry.OnRetry(func(n uint, err error) {
-				t.cfg.OnError(fmt.Errorf("retrying read flash: #%d %w", n, err))
+				t.cfg.OnError(fmt.Errorf("retrying read memory: #%d %w", n, err))
 				t.interFrameLatency += 70
 				if b, errs := t.IDemand(ctx, SetInterFrameLatency, t.interFrameLatency); errs != nil {
 					log.Printf("failed to set frame latency: %d, parent error: %v : %v: resp: %X", t.interFrameLatency, err, errs, b)
@@ -397,9 +429,13 @@ func (t *Client) ReadFlash(ctx context.Context, device byte, lastAddress int, z2
 		if err != nil {
 			return nil, err
 		}
-		t.cfg.OnProgress(float64(bufpnt + int(blockSize) - 1))
+		if phase != nil {
+			phase.Add(bufpnt - before)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	t.cfg.OnProgress(float64(lastAddress))
 	return buf, nil
 }
 
@@ -458,6 +494,9 @@ func demandErr(command Command, d []byte) error {
 
 	if command == 5 {
 		switch d[3] {
+		case 0x01:
+			// Marriage complete
+			return nil
 		case 0xFF:
 			// Critical error; Could not start the secondary loader!
 			return errors.New("failed to start the secondary loader")
@@ -493,7 +532,7 @@ func checkErr(f *gocan.CANFrame) error {
 	case bytes.Equal(f.Data, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}):
 		return errors.New("got blank response message to 0x21, ReadDataByLocalIdentifier")
 	case f.Data[0] == 0x03 && f.Data[1] == 0x7F && f.Data[2] == 0x23:
-		return errors.New("no security access granted")
+		return fmt.Errorf("no security access granted: %w", ecu.ErrSecurityAccessDenied)
 	case f.Data[2] != 0x61 && f.Data[1] != 0x61:
 		if bytes.Equal(f.Data, []byte{0x01, 0x7E, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}) {
 			return fmt.Errorf("incorrect response to 0x21, sendReadDataByLocalIdentifier.  Byte 2 was %X", f.Data[2])
@@ -508,7 +547,7 @@ func (t *Client) ReadDataByLocalIdentifier(ctx context.Context, legionMode bool,
 	frame := gocan.NewFrame(t.canID, payload, gocan.ResponseRequired)
 	resp, err := t.c.SendAndWait(ctx, frame, t.defaultTimeout, t.recvID...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("ReadDataByLocalIdentifier: %w", err)
+		return nil, 0, fmt.Errorf("ReadDataByLocalIdentifier: %w", ecu.NoResponse(err))
 	}
 
 	if err := checkErr(resp); err != nil {
@@ -564,7 +603,7 @@ func (t *Client) ReadDataByLocalIdentifier(ctx context.Context, legionMode bool,
 					seq = 0x20
 				}
 			case <-time.After(t.defaultTimeout * 2):
-				return nil, 0, errors.New("ReadDataByLocalIdentifier: timeout waiting for data")
+				return nil, 0, fmt.Errorf("ReadDataByLocalIdentifier: timeout waiting for data: %w", ecu.ErrNoResponse)
 			}
 		}
 	} else {
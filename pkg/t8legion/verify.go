@@ -0,0 +1,64 @@
+package t8legion
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// Checksum32 is what GetCRC32 answers for a flash holding bin. Despite the
+// name Legion adds up every byte into a 32 bit sum, so the byte order of a
+// swapped MCP bin makes no difference. The T8 build of the loader is
+// encrypted, the sum is the one the readable T5 build calculates for the
+// same command
+func Checksum32(bin []byte) uint32 {
+	var sum uint32
+	for _, b := range bin {
+		sum += uint32(b)
+	}
+	return sum
+}
+
+// Verify compares the checksum-32 of bin with the one Legion calculates over
+// the flash of wish, 0 for the T8 and 1 for the MCP. On a mismatch the
+// partition MD5s asked for with md5type are compared to tell which of the
+// nine partitions differ, partition returns the range and the MD5 of bin for
+// each. A mismatch is an error even if every MD5 matches
+func (t *Client) Verify(ctx context.Context, wish uint16, bin []byte, md5type Command, partition func(int) (ecu.Range, []byte)) error {
+	t.cfg.OnMessage("Comparing checksum-32")
+	resp, err := t.IDemand(ctx, GetCRC32, wish)
+	if err != nil {
+		return err
+	}
+	remote := binary.BigEndian.Uint32(resp)
+	local := Checksum32(bin)
+	t.cfg.OnMessage(fmt.Sprintf("Remote checksum-32 : %08X", remote))
+	t.cfg.OnMessage(fmt.Sprintf("Local checksum-32  : %08X", local))
+	if remote == local {
+		t.cfg.OnMessage("Flash matches bin")
+		return nil
+	}
+
+	t.cfg.OnMessage("Checksum-32 differs, comparing partitions")
+	phase := t.cfg.StartPhase(ecu.PhaseVerify, 9)
+	verr := &ecu.VerifyError{}
+	for i := 1; i <= 9; i++ {
+		md5, err := t.GetMD5(ctx, md5type, uint16(i))
+		if err != nil {
+			return err
+		}
+		r, want := partition(i)
+		if !bytes.Equal(md5, want) {
+			verr.Ranges = append(verr.Ranges, r)
+		}
+		phase.Update(i)
+	}
+	if len(verr.Ranges) == 0 {
+		t.cfg.OnMessage("Every partition MD5 matches, the difference could not be located")
+		verr.Ranges = []ecu.Range{{Start: 0, End: uint32(len(bin))}}
+	}
+	return verr
+}
@@ -0,0 +1,168 @@
+package t8legion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/roffe/gocan"
+	"github.com/roffe/gocanflasher/pkg/ecu"
+)
+
+// Flash writes use the loader commands below
+//
+// Erase, 05 31 <device> 00 <mask hi> <mask lo>
+//
+//	mask has bit 0 set for partition 1 and so on. The loader answers
+//	03 7F 31 78 while busy and 02 71 <device> when done.
+//
+// Write, GMLAN transfer data with the sub function selecting the device, 00
+// for the main flash and 05 for the MCP, and a 4 byte address
+//
+//	10 <len> 36 <sub> <address> followed by consecutive frames, answered
+//	with 01 76 once the block is programmed. MCP addresses are offsets into
+//	the MCP flash and the data is in MCP byte order, the secondary
+//	bootloader has to be started first.
+
+// writeBlockSize is the payload of one transfer data request
+const writeBlockSize = 0xEA
+
+// eraseTimeout is how long the loader may stay busy without answering
+const eraseTimeout = 30 * time.Second
+
+// ErasePartitions erases the partitions set in mask on device, bit 0 is
+// partition 1
+func (t *Client) ErasePartitions(ctx context.Context, device byte, mask uint16) error {
+	if !t.legionRunning {
+		return ecu.ErrBootloaderNotRunning
+	}
+	frame := gocan.NewFrame(t.canID, []byte{0x05, 0x31, device, 0x00, byte(mask >> 8), byte(mask), 0x00, 0x00}, gocan.ResponseRequired)
+
+	sub := t.c.Subscribe(ctx, t.recvID...)
+	defer sub.Close()
+
+	if err := t.c.SendFrame(frame); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case resp := <-sub.Chan():
+			switch {
+			case resp.Data[1] == 0x71:
+				return nil
+			case resp.Data[1] == 0x7F && resp.Data[3] == 0x78:
+				// still busy
+			case resp.Data[1] == 0x7F:
+				return fmt.Errorf("erase: %w", ecu.CheckGMLAN(resp))
+			default:
+				return fmt.Errorf("erase: invalid response %X", resp.Data)
+			}
+		case <-time.After(eraseTimeout):
+			return fmt.Errorf("erase: %w", ecu.ErrNoResponse)
+		}
+	}
+}
+
+// WriteFlash programs data at address on device. Blocks holding only 0xFF are
+// skipped, erased flash already reads that way. phase is advanced by the
+// number of bytes handled if not nil
+func (t *Client) WriteFlash(ctx context.Context, device byte, address int, data []byte, phase *ecu.ProgressReporter) error {
+	if !t.legionRunning {
+		return ecu.ErrBootloaderNotRunning
+	}
+	var sub byte
+	switch device {
+	case EcuByte_T8:
+	case EcuByte_MCP:
+		sub = EcuByte_MCP
+	default:
+		return fmt.Errorf("write to device %d: %w", device, ecu.ErrNotSupported)
+	}
+	for pos := 0; pos < len(data); pos += writeBlockSize {
+		block := data[pos:min(pos+writeBlockSize, len(data))]
+		if !blank(block) {
+			if err := t.writeBlock(ctx, sub, address+pos, block); err != nil {
+				return fmt.Errorf("write 0x%06X: %w", address+pos, err)
+			}
+		}
+		if phase != nil {
+			phase.Add(len(block))
+		}
+	}
+	return nil
+}
+
+// StockDownload writes data at address through the factory bootloader with
+// GMLAN RequestDownload and TransferData, Legion is not used. The ECU must be
+// in programming mode, see EnterProgrammingMode. How the factory loader
+// erases is not known, so every block is sent, blank or not. This has not
+// been checked against a real ECU. phase is advanced by the number of bytes
+// written if not nil
+func (t *Client) StockDownload(ctx context.Context, address int, data []byte, phase *ecu.ProgressReporter) error {
+	if t.legionRunning {
+		return errors.New("stock download: Legion is running, exit it first")
+	}
+	if err := t.gmCall(ctx, func() error { return t.gm.RequestDownload(ctx, false) }); err != nil {
+		return err
+	}
+	for pos := 0; pos < len(data); pos += writeBlockSize {
+		if pos%(writeBlockSize*10) == 0 {
+			t.gm.TesterPresentNoResponseAllowed()
+		}
+		block := data[pos:min(pos+writeBlockSize, len(data))]
+		if err := t.writeBlock(ctx, 0x00, address+pos, block); err != nil {
+			return fmt.Errorf("write 0x%06X: %w", address+pos, err)
+		}
+		if phase != nil {
+			phase.Add(len(block))
+		}
+	}
+	return nil
+}
+
+func (t *Client) writeBlock(ctx context.Context, sub byte, address int, block []byte) error {
+	if err := t.gmCall(ctx, func() error { return t.gm.TransferData(ctx, sub, byte(len(block)+6), address) }); err != nil {
+		return err
+	}
+
+	seq := byte(0x21)
+	for pos := 0; pos < len(block); pos += 7 {
+		payload := make([]byte, 8)
+		payload[0] = seq
+		copy(payload[1:], block[pos:min(pos+7, len(block))])
+		if pos+7 < len(block) {
+			if err := t.c.Send(t.canID, payload, gocan.Outgoing); err != nil {
+				return err
+			}
+		} else {
+			resp, err := t.c.SendAndWait(ctx, gocan.NewFrame(t.canID, payload, gocan.ResponseRequired), t.defaultTimeout*4, t.recvID...)
+			if err != nil {
+				return ecu.NoResponse(err)
+			}
+			if err := ecu.CheckGMLAN(resp); err != nil {
+				return err
+			}
+			if resp.Data[0] != 0x01 || resp.Data[1] != 0x76 {
+				return errors.New("invalid transfer data response")
+			}
+		}
+		seq++
+		if seq > 0x2F {
+			seq = 0x20
+		}
+	}
+	return nil
+}
+
+func blank(b []byte) bool {
+	for _, v := range b {
+		if v != 0xFF {
+			return false
+		}
+	}
+	return true
+}